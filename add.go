@@ -0,0 +1,159 @@
+package transmission
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	"github.com/mfuentesg/transmission/bencode"
+	"github.com/mfuentesg/transmission/internal/magnet"
+)
+
+const (
+	// MaxMetainfoSize bounds the .torrent files read by AddFile and
+	// AddReader.
+	MaxMetainfoSize = 32 << 20
+)
+
+var (
+	ErrInvalidMetainfo   = errors.New("invalid metainfo")
+	ErrInvalidMagnet     = magnet.ErrInvalid
+	ErrInvalidTorrentURL = errors.New("invalid torrent url")
+)
+
+// AddOptions are the torrent-add arguments shared by AddFile, AddReader,
+// AddMagnet and AddURL.
+type AddOptions struct {
+	DownloadDir string
+	// Paused adds the torrent stopped, Start adds it started even when the
+	// daemon adds torrents paused (start-added-torrents off). When neither
+	// is set the daemon setting applies, Paused wins over Start.
+	Paused            bool
+	Start             bool
+	PeerLimit         int64
+	BandwidthPriority int64
+	FilesWanted       []int64
+	FilesUnwanted     []int64
+	PriorityHigh      []int64
+	PriorityLow       []int64
+	PriorityNormal    []int64
+	// Cookies are sent by the daemon when downloading the AddURL file.
+	Cookies string
+}
+
+// arguments returns the torrent-add arguments of the options set. A zero
+// PeerLimit or BandwidthPriority is left out, so the daemon defaults apply
+// instead of a limit of 0 peers.
+func (o AddOptions) arguments() map[string]interface{} {
+	args := make(map[string]interface{})
+
+	set := func(key string, value interface{}, ok bool) {
+		if ok {
+			args[key] = value
+		}
+	}
+
+	set("cookies", o.Cookies, o.Cookies != "")
+	set("download-dir", o.DownloadDir, o.DownloadDir != "")
+	set("paused", o.Paused, o.Paused || o.Start)
+	set("peer-limit", o.PeerLimit, o.PeerLimit != 0)
+	set("bandwidthPriority", o.BandwidthPriority, o.BandwidthPriority != 0)
+	set("files-wanted", o.FilesWanted, len(o.FilesWanted) > 0)
+	set("files-unwanted", o.FilesUnwanted, len(o.FilesUnwanted) > 0)
+	set("priority-high", o.PriorityHigh, len(o.PriorityHigh) > 0)
+	set("priority-low", o.PriorityLow, len(o.PriorityLow) > 0)
+	set("priority-normal", o.PriorityNormal, len(o.PriorityNormal) > 0)
+
+	return args
+}
+
+func (c *Client) add(ctx context.Context, args map[string]interface{}) (AddResult, error) {
+	resp, err := c.fetch(ctx, request{Method: MethodTorrentAdd, Arguments: args})
+	if err != nil {
+		return AddResult{}, err
+	}
+
+	return c.decodeTorrentAdd(resp.Arguments)
+}
+
+// AddFile adds the .torrent file at path.
+func (c *Client) AddFile(ctx context.Context, path string, opts AddOptions) (AddResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return AddResult{}, err
+	}
+	defer f.Close()
+
+	return c.AddReader(ctx, f, opts)
+}
+
+// AddReader adds the .torrent read from r. The content is checked to be a
+// bencoded dictionary with an info dictionary before being sent.
+func (c *Client) AddReader(ctx context.Context, r io.Reader, opts AddOptions) (AddResult, error) {
+	buf, err := ioutil.ReadAll(io.LimitReader(r, MaxMetainfoSize+1))
+	if err != nil {
+		return AddResult{}, err
+	}
+
+	if len(buf) > MaxMetainfoSize {
+		return AddResult{}, fmt.Errorf("%w: larger than %d bytes", ErrInvalidMetainfo, MaxMetainfoSize)
+	}
+
+	if err := validateMetainfo(buf); err != nil {
+		return AddResult{}, err
+	}
+
+	args := opts.arguments()
+	args["metainfo"] = base64.StdEncoding.EncodeToString(buf)
+
+	return c.add(ctx, args)
+}
+
+// AddMagnet adds the torrent of a magnet link, which must carry a btih or
+// btmh exact topic. It is checked as metainfo.ParseMagnet does.
+func (c *Client) AddMagnet(ctx context.Context, uri string, opts AddOptions) (AddResult, error) {
+	if _, err := magnet.Parse(uri); err != nil {
+		return AddResult{}, err
+	}
+
+	args := opts.arguments()
+	args["filename"] = uri
+
+	return c.add(ctx, args)
+}
+
+// AddURL adds the .torrent file the daemon downloads from an http(s) url.
+func (c *Client) AddURL(ctx context.Context, rawURL string, opts AddOptions) (AddResult, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return AddResult{}, fmt.Errorf("%w: %s", ErrInvalidTorrentURL, rawURL)
+	}
+
+	args := opts.arguments()
+	args["filename"] = rawURL
+
+	return c.add(ctx, args)
+}
+
+// validateMetainfo checks buf is a bencoded dictionary with an info
+// dictionary.
+func validateMetainfo(buf []byte) error {
+	var metainfo struct {
+		Info bencode.RawMessage `bencode:"info"`
+	}
+
+	if err := bencode.Unmarshal(buf, &metainfo); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMetainfo, err)
+	}
+
+	if len(metainfo.Info) == 0 || metainfo.Info[0] != 'd' {
+		return fmt.Errorf("%w: missing info dictionary", ErrInvalidMetainfo)
+	}
+
+	return nil
+}
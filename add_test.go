@@ -0,0 +1,162 @@
+package transmission_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+const metainfo = "d8:announce30:http://tracker.example.com/ann4:infod6:lengthi1024e4:name10:debian.iso" +
+	"12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"
+
+func lastArguments(t *testing.T, s *transmissiontest.Server) map[string]interface{} {
+	requests := s.Requests()
+
+	var args map[string]interface{}
+	if err := json.Unmarshal(requests[len(requests)-1].Arguments, &args); err != nil {
+		t.Fatal(err)
+	}
+
+	return args
+}
+
+func TestClient_Add(t *testing.T) {
+	ctx := context.Background()
+
+	s := transmissiontest.NewServer()
+	defer s.Close()
+
+	client := s.Client()
+
+	dir, err := ioutil.TempDir("", "transmission-add")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("should add local files", func(st *testing.T) {
+		path := filepath.Join(dir, "debian.torrent")
+		_ = ioutil.WriteFile(path, []byte(metainfo), 0644)
+
+		torrent, err := client.AddFile(ctx, path, transmission.AddOptions{DownloadDir: "/media", Paused: true})
+		assert.NoError(st, err)
+		assert.NotEmpty(st, torrent.HashString)
+
+		args := lastArguments(st, s)
+		assert.Equal(st, base64.StdEncoding.EncodeToString([]byte(metainfo)), args["metainfo"])
+		assert.Equal(st, "/media", args["download-dir"])
+		assert.Equal(st, true, args["paused"])
+
+		again, err := client.AddFile(ctx, path, transmission.AddOptions{})
+		assert.NoError(st, err)
+		assert.True(st, again.Duplicate)
+		assert.Equal(st, torrent.ID, again.ID)
+
+		_, err = client.AddFile(ctx, filepath.Join(dir, "missing.torrent"), transmission.AddOptions{})
+		assert.True(st, os.IsNotExist(err))
+	})
+
+	t.Run("should reject invalid metainfo before sending it", func(st *testing.T) {
+		sent := len(s.Requests())
+
+		tests := []struct {
+			name  string
+			input string
+		}{
+			{name: "empty", input: ""},
+			{name: "html", input: "<html>not found</html>"},
+			{name: "list", input: "l4:infoe"},
+			{name: "missing info", input: "d8:announce3:urle"},
+			{name: "info is not a dict", input: "d4:info3:abce"},
+			{name: "truncated", input: metainfo[:len(metainfo)-3]},
+			{name: "trailing data", input: metainfo + "garbage"},
+			{name: "invalid integer", input: "d4:infod6:lengthi1x24eee"},
+			{name: "invalid string length", input: "d4:infod4:name99:abcee"},
+		}
+
+		for _, tt := range tests {
+			_, err := client.AddReader(ctx, strings.NewReader(tt.input), transmission.AddOptions{})
+			assert.True(st, errors.Is(err, transmission.ErrInvalidMetainfo), tt.name)
+		}
+
+		assert.Len(st, s.Requests(), sent)
+	})
+
+	t.Run("should reject oversized readers", func(st *testing.T) {
+		big := bytes.Repeat([]byte("a"), transmission.MaxMetainfoSize+1)
+
+		_, err := client.AddReader(ctx, bytes.NewReader(big), transmission.AddOptions{})
+		assert.True(st, errors.Is(err, transmission.ErrInvalidMetainfo))
+	})
+
+	t.Run("should add magnet links", func(st *testing.T) {
+		torrent, err := client.AddMagnet(ctx,
+			"magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=ubuntu.iso", transmission.AddOptions{})
+		assert.NoError(st, err)
+		assert.Equal(st, "ubuntu.iso", torrent.Name)
+
+		// unset limits are left to the daemon defaults
+		args := lastArguments(st, s)
+		assert.NotContains(st, args, "peer-limit")
+		assert.NotContains(st, args, "bandwidthPriority")
+		assert.NotContains(st, args, "paused")
+
+		// checked as metainfo.ParseMagnet does, e.g. the hash length
+		for _, uri := range []string{"http://example.com", "magnet:?dn=ubuntu.iso", "magnet:?xt=urn:sha1:abc",
+			"magnet:?xt=urn:btih:abc", "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&so=2-1"} {
+			_, err := client.AddMagnet(ctx, uri, transmission.AddOptions{})
+			assert.True(st, errors.Is(err, transmission.ErrInvalidMagnet), uri)
+		}
+	})
+
+	t.Run("should send the limits set", func(st *testing.T) {
+		_, err := client.AddMagnet(ctx, "magnet:?xt=urn:btih:89abcdef0123456789abcdef0123456789abcdef&dn=fedora.iso",
+			transmission.AddOptions{PeerLimit: 20, BandwidthPriority: -1, FilesWanted: []int64{0}})
+		assert.NoError(st, err)
+
+		args := lastArguments(st, s)
+		assert.Equal(st, float64(20), args["peer-limit"])
+		assert.Equal(st, float64(-1), args["bandwidthPriority"])
+		assert.Equal(st, []interface{}{float64(0)}, args["files-wanted"])
+	})
+
+	t.Run("should send an explicit start", func(st *testing.T) {
+		for _, test := range []struct { // nolint
+			options transmission.AddOptions
+			paused  bool
+		}{
+			{transmission.AddOptions{Start: true}, false},
+			{transmission.AddOptions{Paused: true}, true},
+			{transmission.AddOptions{Paused: true, Start: true}, true},
+		} {
+			_, err := client.AddURL(ctx, "https://tracker.example.com/start.torrent", test.options)
+			assert.NoError(st, err)
+			assert.Equal(st, test.paused, lastArguments(st, s)["paused"], "%+v", test.options)
+		}
+	})
+
+	t.Run("should add urls with cookies", func(st *testing.T) {
+		_, err := client.AddURL(ctx, "https://tracker.example.com/file.torrent", transmission.AddOptions{Cookies: "uid=1"})
+		assert.NoError(st, err)
+
+		args := lastArguments(st, s)
+		assert.Equal(st, "https://tracker.example.com/file.torrent", args["filename"])
+		assert.Equal(st, "uid=1", args["cookies"])
+
+		for _, uri := range []string{"ftp://example.com/file.torrent", "/tmp/file.torrent", "https://"} {
+			_, err := client.AddURL(ctx, uri, transmission.AddOptions{})
+			assert.True(st, errors.Is(err, transmission.ErrInvalidTorrentURL), uri)
+		}
+	})
+}
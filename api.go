@@ -0,0 +1,51 @@
+package transmission
+
+import "context"
+
+// TorrentAPI groups the torrent-* rpc methods.
+type TorrentAPI interface {
+	TorrentStart(ctx context.Context, args Filter) error
+	TorrentStartNow(ctx context.Context, args Filter) error
+	TorrentStop(ctx context.Context, args Filter) error
+	TorrentVerify(ctx context.Context, args Filter) error
+	TorrentReannounce(ctx context.Context, args Filter) error
+	TorrentGet(ctx context.Context, args TorrentGet) ([]Torrent, error)
+	TorrentRename(ctx context.Context, args TorrentRename) (Torrent, error)
+	TorrentSet(ctx context.Context, args TorrentSet) error
+	TorrentAdd(ctx context.Context, args TorrentAdd) (AddResult, error)
+	TorrentRemove(ctx context.Context, args TorrentRemove) error
+	TorrentMove(ctx context.Context, args TorrentMove) error
+}
+
+// SessionAPI groups the session-* rpc methods and the daemon utilities.
+type SessionAPI interface {
+	Ping(ctx context.Context) error
+	SessionSet(ctx context.Context, args SessionSet) error
+	SessionGet(ctx context.Context) (Session, error)
+	SessionStats(ctx context.Context) (SessionStats, error)
+	SessionClose(ctx context.Context) error
+	FreeSpace(ctx context.Context, args FreeSpace) (FreeSpace, error)
+	PortCheck(ctx context.Context) (PortCheck, error)
+	BlockListUpdate(ctx context.Context) (BlockList, error)
+}
+
+// QueueAPI groups the queue-move-* rpc methods.
+type QueueAPI interface {
+	QueueMoveTop(ctx context.Context, args Filter) error
+	QueueMoveBottom(ctx context.Context, args Filter) error
+	QueueMoveUp(ctx context.Context, args Filter) error
+	QueueMoveDown(ctx context.Context, args Filter) error
+}
+
+// API is implemented by Client and Fake. Code depending on it, or on one of
+// the smaller interfaces, can be unit tested without a daemon.
+type API interface {
+	TorrentAPI
+	SessionAPI
+	QueueAPI
+}
+
+var (
+	_ API = (*Client)(nil)
+	_ API = (*Fake)(nil)
+)
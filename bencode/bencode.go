@@ -0,0 +1,142 @@
+// Package bencode implements the encoding of .torrent and .resume files.
+//
+// Values map to Go types as follows: integers to int64 (or any sized integer
+// type), strings to string or []byte, lists to slices and dictionaries to
+// map[string]T or structs. Struct fields use the "bencode" tag for their key,
+// with the "omitempty" option, and "-" to skip them.
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	maxDepth = 512
+)
+
+var (
+	ErrSyntax          = errors.New("bencode: syntax error")
+	ErrNonCanonical    = errors.New("bencode: non canonical encoding")
+	ErrUnsupportedType = errors.New("bencode: unsupported type")
+	ErrInvalidTarget   = errors.New("bencode: invalid decode target")
+	ErrTypeMismatch    = errors.New("bencode: value does not match the target type")
+)
+
+// RawMessage is an encoded value kept as it is, e.g. to hash the exact bytes
+// of a metainfo info dictionary.
+type RawMessage []byte
+
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
+
+type Unmarshaler interface {
+	UnmarshalBencode([]byte) error
+}
+
+var (
+	rawMessageType  = reflect.TypeOf(RawMessage(nil))
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+type field struct {
+	key       string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// structFields returns the encoded fields of t, embedded structs without tag
+// being flattened. The fields are sorted by key.
+func structFields(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	fields := collectFields(t, nil)
+	seen := make(map[string]bool, len(fields))
+	unique := fields[:0]
+
+	// the shallowest field wins, as with encoding/json
+	for _, f := range fields {
+		if !seen[f.key] {
+			seen[f.key] = true
+			unique = append(unique, f)
+		}
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool { return unique[i].key < unique[j].key })
+	fieldCache.Store(t, unique)
+
+	return unique
+}
+
+func collectFields(t reflect.Type, index []int) []field {
+	var fields, embedded []field
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("bencode")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if comma := strings.IndexByte(tag, ','); comma >= 0 {
+			name, opts = tag[:comma], tag[comma+1:]
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if sf.PkgPath != "" && sf.Type.Kind() == reflect.Ptr {
+				// can not be allocated through reflection
+				continue
+			}
+			embedded = append(embedded, collectFields(ft, fieldIndex)...)
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, field{key: name, index: fieldIndex, omitEmpty: strings.Contains(","+opts+",", ",omitempty,")})
+	}
+
+	return append(fields, embedded...)
+}
+
+// fieldByIndex returns the field of v at index, allocating the nil embedded
+// pointers when alloc is set. It returns false when such a pointer is nil.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v, true
+}
@@ -0,0 +1,242 @@
+package bencode
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type file struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type info struct {
+	Files       []file `bencode:"files,omitempty"`
+	Length      int64  `bencode:"length,omitempty"`
+	Name        string `bencode:"name"`
+	PieceLength int64  `bencode:"piece length"`
+	Pieces      []byte `bencode:"pieces"`
+	Private     bool   `bencode:"private,omitempty"`
+}
+
+type metainfo struct {
+	Announce     string     `bencode:"announce,omitempty"`
+	AnnounceList [][]string `bencode:"announce-list,omitempty"`
+	Comment      string     `bencode:"comment,omitempty"`
+	Info         RawMessage `bencode:"info"`
+	Ignored      string     `bencode:"-"`
+	unexported   string     // nolint
+}
+
+type upper string
+
+func (u upper) MarshalBencode() ([]byte, error) {
+	return Marshal(strings.ToUpper(string(u)))
+}
+
+func (u *upper) UnmarshalBencode(data []byte) error {
+	var s string
+	if err := Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*u = upper(strings.ToLower(s))
+	return nil
+}
+
+type Embedded struct {
+	Source string `bencode:"source,omitempty"`
+}
+
+type withEmbedded struct {
+	Embedded
+	Name  string `bencode:"name"`
+	Upper upper  `bencode:"upper"`
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{name: "should encode integers", input: -42, expected: "i-42e"},
+		{name: "should encode unsigned integers", input: uint64(1) << 63, expected: "i9223372036854775808e"},
+		{name: "should encode booleans as integers", input: true, expected: "i1e"},
+		{name: "should encode strings", input: "spam", expected: "4:spam"},
+		{name: "should encode byte slices as strings", input: []byte{0, 1}, expected: "2:\x00\x01"},
+		{name: "should encode byte arrays as strings", input: [2]byte{'a', 'b'}, expected: "2:ab"},
+		{name: "should encode lists", input: []interface{}{"a", 1, []string{}}, expected: "l1:ai1elee"},
+		{
+			name:     "should encode maps with sorted keys",
+			input:    map[string]interface{}{"b": 1, "a": "x", "nil": nil},
+			expected: "d1:a1:x1:bi1ee",
+		},
+		{
+			name: "should encode structs with sorted keys and omitempty",
+			input: info{
+				Name:        "debian.iso",
+				PieceLength: 16384,
+				Pieces:      []byte("aaaa"),
+			},
+			expected: "d4:name10:debian.iso12:piece lengthi16384e6:pieces4:aaaae",
+		},
+		{
+			name:     "should pass raw messages through",
+			input:    metainfo{Announce: "http://t", Info: RawMessage("d1:ai1ee"), Ignored: "x"},
+			expected: "d8:announce8:http://t4:infod1:ai1eee",
+		},
+		{
+			name:     "should flatten embedded structs and call marshalers",
+			input:    withEmbedded{Embedded: Embedded{Source: "ABC"}, Name: "n", Upper: "up"},
+			expected: "d4:name1:n6:source3:ABC5:upper2:UPe",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			// nolint
+			buf, err := Marshal(test.input)
+			assert.NoError(st, err)
+			// nolint
+			assert.Equal(st, test.expected, string(buf))
+		})
+	}
+
+	t.Run("should reject unsupported values", func(st *testing.T) {
+		for _, input := range []interface{}{nil, 1.5, map[int]string{1: "a"}, make(chan int)} {
+			_, err := Marshal(input)
+			assert.True(st, errors.Is(err, ErrUnsupportedType), "%T", input)
+		}
+	})
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Run("should decode into generic values", func(st *testing.T) {
+		var v interface{}
+		assert.NoError(st, Unmarshal([]byte("d1:ai-1e1:bl3:fooi0eee"), &v))
+		assert.Equal(st, map[string]interface{}{
+			"a": int64(-1),
+			"b": []interface{}{"foo", int64(0)},
+		}, v)
+	})
+
+	t.Run("should decode structs keeping the raw info", func(st *testing.T) {
+		data := "d8:announce8:http://t13:announce-listll8:http://tel8:http://uee" +
+			"7:unknowni1e4:infod6:lengthi5e4:name3:abc12:piece lengthi1e6:pieces1:x7:privatei1eee"
+
+		var m metainfo
+		assert.NoError(st, Unmarshal([]byte(data), &m))
+		assert.Equal(st, "http://t", m.Announce)
+		assert.Equal(st, [][]string{{"http://t"}, {"http://u"}}, m.AnnounceList)
+		assert.Equal(st, "d6:lengthi5e4:name3:abc12:piece lengthi1e6:pieces1:x7:privatei1ee", string(m.Info))
+
+		var i info
+		assert.NoError(st, Unmarshal(m.Info, &i))
+		assert.Equal(st, info{Length: 5, Name: "abc", PieceLength: 1, Pieces: []byte("x"), Private: true}, i)
+
+		// the hash of the raw bytes is the infohash
+		assert.Equal(st, sha1.Sum([]byte("d6:lengthi5e4:name3:abc12:piece lengthi1e6:pieces1:x7:privatei1ee")), sha1.Sum(m.Info))
+	})
+
+	t.Run("should decode into maps, arrays, pointers and unmarshalers", func(st *testing.T) {
+		var m map[string]*int
+		assert.NoError(st, Unmarshal([]byte("d1:ai1e1:bi2ee"), &m))
+		assert.Equal(st, 2, *m["b"])
+
+		var hash [4]byte
+		assert.NoError(st, Unmarshal([]byte("4:abcd"), &hash))
+		assert.Equal(st, [4]byte{'a', 'b', 'c', 'd'}, hash)
+
+		var e withEmbedded
+		assert.NoError(st, Unmarshal([]byte("d4:name1:n6:source3:ABC5:upper2:UPe"), &e))
+		assert.Equal(st, withEmbedded{Embedded: Embedded{Source: "ABC"}, Name: "n", Upper: "up"}, e)
+	})
+
+	t.Run("should report syntax errors", func(st *testing.T) {
+		for _, input := range []string{
+			"", "i12", "ie", "i1.5e", "4:abc", "-1:a", "l", "d1:ae", "di1ei1ee", "x", "i1ei2e", "3:abcd",
+		} {
+			var v interface{}
+			err := Unmarshal([]byte(input), &v)
+			assert.True(st, errors.Is(err, ErrSyntax), "%q: %v", input, err)
+		}
+	})
+
+	t.Run("should report type mismatches", func(st *testing.T) {
+		var i info
+		err := Unmarshal([]byte("d4:namei1ee"), &i)
+		assert.True(st, errors.Is(err, ErrTypeMismatch))
+		assert.Contains(st, err.Error(), "offset 7")
+
+		var small int8
+		assert.True(st, errors.Is(Unmarshal([]byte("i300e"), &small), ErrTypeMismatch))
+
+		var hash [20]byte
+		assert.True(st, errors.Is(Unmarshal([]byte("3:abc"), &hash), ErrTypeMismatch))
+
+		assert.True(st, errors.Is(Unmarshal([]byte("i1e"), i), ErrInvalidTarget))
+	})
+
+	t.Run("should accept non canonical values unless strict", func(st *testing.T) {
+		for _, input := range []string{"d1:bi1e1:ai2ee", "d1:ai1e1:ai2ee", "i03e", "i-0e", "03:abc"} {
+			var v interface{}
+			assert.NoError(st, Unmarshal([]byte(input), &v), input)
+
+			d := NewDecoder(strings.NewReader(input))
+			d.DisallowNonCanonical()
+			err := d.Decode(&v)
+			assert.True(st, errors.Is(err, ErrNonCanonical), "%q: %v", input, err)
+		}
+	})
+}
+
+func TestDecoder(t *testing.T) {
+	t.Run("should decode successive values", func(st *testing.T) {
+		d := NewDecoder(strings.NewReader("i1e4:spamd1:ai2ee"))
+
+		var n int
+		var s string
+		var m map[string]int
+
+		assert.NoError(st, d.Decode(&n))
+		assert.NoError(st, d.Decode(&s))
+		assert.NoError(st, d.Decode(&m))
+		assert.Equal(st, io.EOF, d.Decode(&n))
+		assert.Equal(st, 1, n)
+		assert.Equal(st, "spam", s)
+		assert.Equal(st, map[string]int{"a": 2}, m)
+	})
+
+	t.Run("should report truncated values", func(st *testing.T) {
+		for _, input := range []string{"i1", "l4:spam", "10:abc", "d1:a", "ix"} {
+			var v interface{}
+			err := NewDecoder(strings.NewReader(input)).Decode(&v)
+			assert.True(st, errors.Is(err, ErrSyntax), "%q: %v", input, err)
+		}
+	})
+
+	t.Run("should report offsets across values", func(st *testing.T) {
+		d := NewDecoder(strings.NewReader("i1ed1:bi1e1:ai2ee"))
+		d.DisallowNonCanonical()
+
+		var v interface{}
+		assert.NoError(st, d.Decode(&v))
+		err := d.Decode(&v)
+		assert.Contains(st, err.Error(), "offset 10")
+	})
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+
+	e := NewEncoder(&buf)
+	assert.NoError(t, e.Encode(1))
+	assert.NoError(t, e.Encode("a"))
+	assert.Equal(t, "i1e1:a", buf.String())
+}
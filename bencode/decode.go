@@ -0,0 +1,532 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// Unmarshal decodes data, which must hold a single value, into v. Unknown
+// dictionary keys are ignored.
+func Unmarshal(data []byte, v interface{}) error {
+	return unmarshal(data, v, false, 0)
+}
+
+func unmarshal(data []byte, v interface{}, strict bool, offset int64) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("%w: %T", ErrInvalidTarget, v)
+	}
+
+	d := decoder{data: data, strict: strict, offset: offset}
+	if err := d.value(target.Elem(), 0); err != nil {
+		return err
+	}
+
+	if d.pos != len(d.data) {
+		return d.syntaxError("unexpected data after the value")
+	}
+
+	return nil
+}
+
+// Decoder reads successive values from a stream.
+type Decoder struct {
+	r      *bufio.Reader
+	strict bool
+	offset int64
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// DisallowNonCanonical makes Decode reject the values a canonical encoder
+// would not produce: unsorted or duplicated dictionary keys, and integers or
+// string lengths with leading zeros. Hashes of such values, e.g. infohashes,
+// differ between implementations.
+func (d *Decoder) DisallowNonCanonical() {
+	d.strict = true
+}
+
+// Decode reads the next value into v. It returns io.EOF when the stream ends
+// between two values.
+func (d *Decoder) Decode(v interface{}) error {
+	if _, err := d.r.Peek(1); err == io.EOF {
+		return io.EOF
+	}
+
+	var buf bytes.Buffer
+	if err := d.read(&buf, 0); err != nil {
+		return err
+	}
+
+	offset := d.offset
+	d.offset += int64(buf.Len())
+
+	return unmarshal(buf.Bytes(), v, d.strict, offset)
+}
+
+// read copies the next value to buf, checking only its structure.
+func (d *Decoder) read(buf *bytes.Buffer, depth int) error {
+	if depth > maxDepth {
+		return d.syntaxError(buf, "nested too deep")
+	}
+
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return d.syntaxError(buf, "unexpected end of data")
+	}
+	buf.WriteByte(c)
+
+	switch {
+	case c == 'i':
+		return d.readUntil(buf, 'e')
+	case c >= '0' && c <= '9':
+		start := buf.Len() - 1
+		if err := d.readUntil(buf, ':'); err != nil {
+			return err
+		}
+
+		length, err := strconv.ParseInt(string(buf.Bytes()[start:buf.Len()-1]), 10, 64)
+		if err != nil {
+			return d.syntaxError(buf, "invalid string length")
+		}
+
+		if n, _ := io.CopyN(buf, d.r, length); n != length {
+			return d.syntaxError(buf, "unexpected end of data")
+		}
+
+		return nil
+	case c == 'l' || c == 'd':
+		for {
+			next, err := d.r.Peek(1)
+			if err != nil {
+				return d.syntaxError(buf, "unexpected end of data")
+			}
+
+			if next[0] == 'e' {
+				_, _ = d.r.ReadByte()
+				buf.WriteByte('e')
+				return nil
+			}
+
+			if err := d.read(buf, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return d.syntaxError(buf, fmt.Sprintf("unexpected %q", c))
+}
+
+func (d *Decoder) readUntil(buf *bytes.Buffer, delim byte) error {
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return d.syntaxError(buf, "unexpected end of data")
+		}
+		buf.WriteByte(c)
+
+		if c == delim {
+			return nil
+		}
+
+		// integers and lengths are made of digits, avoid reading a whole
+		// stream looking for delim
+		if (c < '0' || c > '9') && c != '-' {
+			return d.syntaxError(buf, fmt.Sprintf("unexpected %q", c))
+		}
+	}
+}
+
+func (d *Decoder) syntaxError(buf *bytes.Buffer, msg string) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrSyntax, d.offset+int64(buf.Len()), msg)
+}
+
+type decoder struct {
+	data   []byte
+	pos    int
+	strict bool
+	offset int64
+}
+
+func (d *decoder) syntaxError(msg string) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrSyntax, d.offset+int64(d.pos), msg)
+}
+
+func (d *decoder) canonicalError(msg string) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrNonCanonical, d.offset+int64(d.pos), msg)
+}
+
+func (d *decoder) mismatch(kind string, t reflect.Type) error {
+	return fmt.Errorf("%w at offset %d: %s into %s", ErrTypeMismatch, d.offset+int64(d.pos), kind, t)
+}
+
+func (d *decoder) peek() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, d.syntaxError("unexpected end of data")
+	}
+
+	return d.data[d.pos], nil
+}
+
+// value decodes the value at pos into v.
+func (d *decoder) value(v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return d.syntaxError("nested too deep")
+	}
+
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+
+	if v.Type() == rawMessageType {
+		start := d.pos
+		if err := d.skip(depth); err != nil {
+			return err
+		}
+		v.SetBytes(append([]byte(nil), d.data[start:d.pos]...))
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		start := d.pos
+		if err := d.skip(depth); err != nil {
+			return err
+		}
+		return v.Addr().Interface().(Unmarshaler).UnmarshalBencode(d.data[start:d.pos])
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.value(v.Elem(), depth+1)
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return d.mismatch("value", v.Type())
+		}
+		generic, err := d.generic(depth)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(generic))
+		return nil
+	}
+
+	switch {
+	case c == 'i':
+		return d.integer(v)
+	case c >= '0' && c <= '9':
+		return d.bytes(v)
+	case c == 'l':
+		return d.list(v, depth)
+	case c == 'd':
+		return d.dict(v, depth)
+	}
+
+	return d.syntaxError(fmt.Sprintf("unexpected %q", c))
+}
+
+// generic decodes the value at pos as int64, string, []interface{} or
+// map[string]interface{}.
+func (d *decoder) generic(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, d.syntaxError("nested too deep")
+	}
+
+	c, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c == 'i':
+		return d.readInt()
+	case c >= '0' && c <= '9':
+		b, err := d.readBytes()
+		return string(b), err
+	case c == 'l':
+		d.pos++
+		list := make([]interface{}, 0)
+		for {
+			if c, err := d.peek(); err != nil {
+				return nil, err
+			} else if c == 'e' {
+				d.pos++
+				return list, nil
+			}
+
+			item, err := d.generic(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+	case c == 'd':
+		dict := make(map[string]interface{})
+		err := d.entries(func(key string) error {
+			value, err := d.generic(depth + 1)
+			dict[key] = value
+			return err
+		})
+		return dict, err
+	}
+
+	return nil, d.syntaxError(fmt.Sprintf("unexpected %q", c))
+}
+
+func (d *decoder) integer(v reflect.Value) error {
+	start := d.pos
+
+	n, err := d.readInt()
+	if err != nil {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(n) {
+			d.pos = start
+			return d.mismatch("integer "+strconv.FormatInt(n, 10), v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n < 0 || v.OverflowUint(uint64(n)) {
+			d.pos = start
+			return d.mismatch("integer "+strconv.FormatInt(n, 10), v.Type())
+		}
+		v.SetUint(uint64(n))
+	case reflect.Bool:
+		v.SetBool(n != 0)
+	default:
+		d.pos = start
+		return d.mismatch("integer", v.Type())
+	}
+
+	return nil
+}
+
+func (d *decoder) bytes(v reflect.Value) error {
+	start := d.pos
+
+	b, err := d.readBytes()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(string(b))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(append([]byte(nil), b...))
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		if len(b) != v.Len() {
+			d.pos = start
+			return d.mismatch(fmt.Sprintf("string of length %d", len(b)), v.Type())
+		}
+		reflect.Copy(v, reflect.ValueOf(b))
+	default:
+		d.pos = start
+		return d.mismatch("string", v.Type())
+	}
+
+	return nil
+}
+
+func (d *decoder) list(v reflect.Value, depth int) error {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+	default:
+		return d.mismatch("list", v.Type())
+	}
+
+	d.pos++
+	i := 0
+
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+
+	for {
+		c, err := d.peek()
+		if err != nil {
+			return err
+		}
+
+		if c == 'e' {
+			d.pos++
+			break
+		}
+
+		switch {
+		case v.Kind() == reflect.Slice:
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := d.value(item, depth+1); err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, item))
+		case i < v.Len():
+			if err := d.value(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		default:
+			// extra items of arrays are dropped
+			if err := d.skip(depth + 1); err != nil {
+				return err
+			}
+		}
+
+		i++
+	}
+
+	return nil
+}
+
+func (d *decoder) dict(v reflect.Value, depth int) error {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return d.mismatch("dictionary", v.Type())
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		return d.entries(func(key string) error {
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := d.value(item, depth+1); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), item)
+			return nil
+		})
+	case reflect.Struct:
+		fields := structFields(v.Type())
+
+		return d.entries(func(key string) error {
+			for _, f := range fields {
+				if f.key == key {
+					field, _ := fieldByIndex(v, f.index, true)
+					return d.value(field, depth+1)
+				}
+			}
+
+			return d.skip(depth + 1)
+		})
+	}
+
+	return d.mismatch("dictionary", v.Type())
+}
+
+// entries reads the dictionary at pos, calling fn for each key with pos on
+// its value.
+func (d *decoder) entries(fn func(key string) error) error {
+	d.pos++
+
+	var previous []byte
+	first := true
+
+	for {
+		c, err := d.peek()
+		if err != nil {
+			return err
+		}
+
+		if c == 'e' {
+			d.pos++
+			return nil
+		}
+
+		if c < '0' || c > '9' {
+			return d.syntaxError("dictionary key is not a string")
+		}
+
+		start := d.pos
+		key, err := d.readBytes()
+		if err != nil {
+			return err
+		}
+
+		if d.strict && !first && bytes.Compare(previous, key) >= 0 {
+			d.pos = start
+			return d.canonicalError(fmt.Sprintf("dictionary key %q not sorted", key))
+		}
+		previous, first = key, false
+
+		if err := fn(string(key)); err != nil {
+			return err
+		}
+	}
+}
+
+// skip moves pos after the value at pos, checking its syntax.
+func (d *decoder) skip(depth int) error {
+	_, err := d.generic(depth)
+	return err
+}
+
+func (d *decoder) readInt() (int64, error) {
+	d.pos++
+
+	end := bytes.IndexByte(d.data[d.pos:], 'e')
+	if end < 0 {
+		return 0, d.syntaxError("unterminated integer")
+	}
+
+	digits := d.data[d.pos : d.pos+end]
+	n, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil || len(digits) == 0 || digits[0] == '+' {
+		return 0, d.syntaxError(fmt.Sprintf("invalid integer %q", digits))
+	}
+
+	if d.strict && !canonicalInt(digits) {
+		return 0, d.canonicalError(fmt.Sprintf("integer %q", digits))
+	}
+
+	d.pos += end + 1
+
+	return n, nil
+}
+
+func (d *decoder) readBytes() ([]byte, error) {
+	colon := bytes.IndexByte(d.data[d.pos:], ':')
+	if colon < 0 {
+		return nil, d.syntaxError("invalid string")
+	}
+
+	digits := d.data[d.pos : d.pos+colon]
+	length, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil || length < 0 || digits[0] < '0' || digits[0] > '9' {
+		return nil, d.syntaxError(fmt.Sprintf("invalid string length %q", digits))
+	}
+
+	if d.strict && !canonicalInt(digits) {
+		return nil, d.canonicalError(fmt.Sprintf("string length %q", digits))
+	}
+
+	start := d.pos + colon + 1
+	if length > int64(len(d.data)-start) {
+		return nil, d.syntaxError("unexpected end of data")
+	}
+
+	d.pos = start + int(length)
+
+	return d.data[start:d.pos], nil
+}
+
+// canonicalInt reports whether digits has no leading zeros and is not "-0".
+func canonicalInt(digits []byte) bool {
+	if digits[0] == '-' {
+		return len(digits) > 1 && digits[1] != '0'
+	}
+
+	return len(digits) == 1 || digits[0] != '0'
+}
@@ -0,0 +1,203 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Marshal returns the canonical encoding of v: dictionary keys are sorted,
+// nil pointers and interfaces, and empty omitempty fields, are left out.
+// Booleans are encoded as 0 or 1.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := encode(&buf, reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type Encoder struct {
+	w io.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the encoding of v.
+func (e *Encoder) Encode(v interface{}) error {
+	buf, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(buf)
+
+	return err
+}
+
+func encode(buf *bytes.Buffer, v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%w: nested too deep", ErrUnsupportedType)
+	}
+
+	if !v.IsValid() {
+		return fmt.Errorf("%w: nil", ErrUnsupportedType)
+	}
+
+	if v.Type() == rawMessageType {
+		if v.Len() == 0 {
+			return fmt.Errorf("%w: empty RawMessage", ErrUnsupportedType)
+		}
+		buf.Write(v.Bytes())
+		return nil
+	}
+
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		v = v.Addr()
+	}
+
+	if v.Type().Implements(marshalerType) && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+		raw, err := v.Interface().(Marshaler).MarshalBencode()
+		if err != nil {
+			return err
+		}
+		buf.Write(raw)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return fmt.Errorf("%w: nil", ErrUnsupportedType)
+		}
+		return encode(buf, v.Elem(), depth+1)
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteString("i1e")
+		} else {
+			buf.WriteString("i0e")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteByte('i')
+		buf.WriteString(strconv.FormatInt(v.Int(), 10))
+		buf.WriteByte('e')
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		buf.WriteByte('i')
+		buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+		buf.WriteByte('e')
+	case reflect.String:
+		writeString(buf, v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			writeBytes(buf, byteSlice(v))
+			return nil
+		}
+
+		buf.WriteByte('l')
+		for i := 0; i < v.Len(); i++ {
+			if isNil(v.Index(i)) {
+				continue
+			}
+			if err := encode(buf, v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%w: map key %s", ErrUnsupportedType, v.Type().Key())
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		buf.WriteByte('d')
+		for _, key := range keys {
+			value := v.MapIndex(key)
+			if isNil(value) {
+				continue
+			}
+			writeString(buf, key.String())
+			if err := encode(buf, value, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case reflect.Struct:
+		buf.WriteByte('d')
+		for _, f := range structFields(v.Type()) {
+			value, ok := fieldByIndex(v, f.index, false)
+			if !ok || isNil(value) || (f.omitEmpty && isEmpty(value)) {
+				continue
+			}
+			writeString(buf, f.key)
+			if err := encode(buf, value, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, v.Type())
+	}
+
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(strconv.Itoa(len(s)))
+	buf.WriteByte(':')
+	buf.WriteString(s)
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	buf.WriteString(strconv.Itoa(len(b)))
+	buf.WriteByte(':')
+	buf.Write(b)
+}
+
+func byteSlice(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+
+	return b
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		// a nil RawMessage has nothing to write
+		return v.Type() == rawMessageType && v.Len() == 0
+	}
+
+	return false
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+
+	return false
+}
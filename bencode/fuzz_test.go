@@ -0,0 +1,55 @@
+//go:build go1.18
+// +build go1.18
+
+package bencode
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzUnmarshal(f *testing.F) {
+	f.Add([]byte("d8:announce8:http://t4:infod6:lengthi5e4:name3:abc12:piece lengthi1e6:pieces1:xee"))
+	f.Add([]byte("l4:spami-3ee"))
+	f.Add([]byte("d1:bi1e1:ai2ee"))
+	f.Add([]byte("i03e"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v interface{}
+		if err := Unmarshal(data, &v); err != nil {
+			return
+		}
+
+		encoded, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("decoded %q but could not encode it back: %v", data, err)
+		}
+
+		// canonical inputs must round trip byte for byte
+		d := NewDecoder(bytes.NewReader(data))
+		d.DisallowNonCanonical()
+		if d.Decode(&v) == nil && !bytes.Equal(encoded, data) {
+			t.Fatalf("canonical %q encoded as %q", data, encoded)
+		}
+
+		var again interface{}
+		if err := Unmarshal(encoded, &again); err != nil {
+			t.Fatalf("could not decode %q: %v", encoded, err)
+		}
+	})
+}
+
+func FuzzUnmarshalStruct(f *testing.F) {
+	f.Add([]byte("d8:announce8:http://t13:announce-listll8:http://teee4:infod4:name1:aee"))
+	f.Add([]byte("d4:infod5:filesld6:lengthi1e4:pathl1:aeeee"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var m metainfo
+		if Unmarshal(data, &m) != nil || len(m.Info) == 0 {
+			return
+		}
+
+		var i info
+		_ = Unmarshal(m.Info, &i)
+	})
+}
@@ -0,0 +1,367 @@
+package transmission
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	EnvURL          = "TRANSMISSION_URL"
+	EnvUsername     = "TRANSMISSION_USER"
+	EnvPassword     = "TRANSMISSION_PASSWORD"
+	EnvPasswordFile = "TRANSMISSION_PASSWORD_FILE"
+	EnvConfigFile   = "TRANSMISSION_CONFIG"
+	EnvProfile      = "TRANSMISSION_PROFILE"
+	EnvNetrc        = "NETRC"
+
+	DefaultProfile = "default"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile not found in config file")
+)
+
+// Config describes how to reach a daemon. It can be loaded from a config file
+// (LoadConfig), from the environment (ConfigFromEnv) or built by hand.
+type Config struct {
+	URL          string `json:"url,omitempty" yaml:"url,omitempty"`
+	Username     string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password     string `json:"password,omitempty" yaml:"password,omitempty"`
+	PasswordFile string `json:"password-file,omitempty" yaml:"password-file,omitempty"`
+	CAFile       string `json:"ca-file,omitempty" yaml:"ca-file,omitempty"`
+	CertFile     string `json:"cert-file,omitempty" yaml:"cert-file,omitempty"`
+	KeyFile      string `json:"key-file,omitempty" yaml:"key-file,omitempty"`
+	ServerName   string `json:"server-name,omitempty" yaml:"server-name,omitempty"`
+	Fingerprint  string `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	MaxRetries   int    `json:"max-retries,omitempty" yaml:"max-retries,omitempty"`
+}
+
+// configFile is the layout of the json and yaml config files, e.g.
+//
+//	default-profile: home
+//	profiles:
+//	  home:
+//	    url: nas.local
+//	    username: admin
+//	    password-file: /run/secrets/transmission
+//	  seedbox:
+//	    url: https://seedbox.example.com/transmission/rpc
+type configFile struct {
+	DefaultProfile string            `json:"default-profile" yaml:"default-profile"`
+	Profiles       map[string]Config `json:"profiles" yaml:"profiles"`
+}
+
+// LoadConfig reads the given profile from a json (.json extension) or yaml
+// config file. An empty profile selects the file default profile, or
+// DefaultProfile when the file does not define one.
+func LoadConfig(path, profile string) (Config, error) {
+	var file configFile
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(buf, &file)
+	} else {
+		err = yaml.Unmarshal(buf, &file)
+	}
+
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if profile == "" {
+		profile = file.DefaultProfile
+	}
+
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	config, ok := file.Profiles[profile]
+	if !ok {
+		return Config{}, fmt.Errorf("%w: %q", ErrProfileNotFound, profile)
+	}
+
+	return config, nil
+}
+
+// ConfigFromEnv builds a Config with the following precedence, from highest
+// to lowest:
+//
+//  1. TRANSMISSION_URL, TRANSMISSION_USER, TRANSMISSION_PASSWORD and
+//     TRANSMISSION_PASSWORD_FILE variables
+//  2. the TRANSMISSION_PROFILE profile of the TRANSMISSION_CONFIG file
+//  3. the ~/.netrc (or NETRC) entry matching the url host, only used when
+//     no credentials were found in the previous sources
+//
+// Values are merged field by field, except the credentials of the profile
+// (username, password, password file and client certificate), which are
+// ignored when TRANSMISSION_URL points to another host than the profile
+// url. Within the same source, a password always wins over a password file.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		URL:          os.Getenv(EnvURL),
+		Username:     os.Getenv(EnvUsername),
+		Password:     os.Getenv(EnvPassword),
+		PasswordFile: os.Getenv(EnvPasswordFile),
+	}
+
+	// a password file from the environment must not be shadowed by a
+	// password coming from the config file
+	if config.PasswordFile != "" && config.Password == "" {
+		password, err := readPasswordFile(config.PasswordFile)
+		if err != nil {
+			return config, err
+		}
+		config.Password = password
+	}
+
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		file, err := LoadConfig(path, os.Getenv(EnvProfile))
+		if err != nil {
+			return config, err
+		}
+
+		// credentials are only sent to the host they were configured for
+		if config.URL != "" && urlHost(config.URL) != urlHost(file.URL) {
+			file.Username, file.Password, file.PasswordFile = "", "", ""
+			file.CertFile, file.KeyFile = "", ""
+		}
+
+		config = config.merge(file)
+	}
+
+	if config.Username == "" && config.Password == "" && config.PasswordFile == "" {
+		login, password, err := netrcCredentials(config.URL)
+		if err != nil {
+			return config, err
+		}
+
+		config.Username, config.Password = login, password
+	}
+
+	return config, nil
+}
+
+// merge fills the empty fields of config with the ones of base.
+func (config Config) merge(base Config) Config {
+	fill := func(value *string, fallback string) {
+		if *value == "" {
+			*value = fallback
+		}
+	}
+
+	fill(&config.URL, base.URL)
+	fill(&config.Username, base.Username)
+	fill(&config.Password, base.Password)
+	fill(&config.PasswordFile, base.PasswordFile)
+	fill(&config.CAFile, base.CAFile)
+	fill(&config.CertFile, base.CertFile)
+	fill(&config.KeyFile, base.KeyFile)
+	fill(&config.ServerName, base.ServerName)
+	fill(&config.Fingerprint, base.Fingerprint)
+
+	if config.MaxRetries == 0 {
+		config.MaxRetries = base.MaxRetries
+	}
+
+	return config
+}
+
+// Options converts the config into client options, reading the password
+// file when no password is set.
+func (config Config) Options() ([]Option, error) {
+	password := config.Password
+	if password == "" && config.PasswordFile != "" {
+		var err error
+		if password, err = readPasswordFile(config.PasswordFile); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []Option{WithURL(config.URL)}
+
+	if config.Username != "" || password != "" {
+		opts = append(opts, WithBasicAuth(config.Username, password))
+	}
+
+	if config.MaxRetries != 0 {
+		opts = append(opts, WithMaxRetries(config.MaxRetries))
+	}
+
+	if config.CAFile != "" {
+		opts = append(opts, WithCAFile(config.CAFile))
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		opts = append(opts, WithClientCertificate(config.CertFile, config.KeyFile))
+	}
+
+	if config.ServerName != "" {
+		opts = append(opts, WithServerName(config.ServerName))
+	}
+
+	if config.Fingerprint != "" {
+		opts = append(opts, WithCertificateFingerprint(config.Fingerprint))
+	}
+
+	return opts, nil
+}
+
+// NewFromConfig creates a client from config. The given options are applied
+// afterwards, so they take precedence over the config values.
+func NewFromConfig(config Config, opts ...Option) (*Client, error) {
+	configOpts, err := config.Options()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(append(configOpts, opts...)...)
+}
+
+// NewFromEnv creates a client from ConfigFromEnv. The given options take
+// precedence over any value found in the environment.
+func NewFromEnv(opts ...Option) (*Client, error) {
+	config, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFromConfig(config, opts...)
+}
+
+func readPasswordFile(path string) (string, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+
+	return strings.TrimRight(string(buf), "\r\n"), nil
+}
+
+// netrcCredentials looks up the login and password of the rpc url host in
+// the netrc file. A missing file is not an error.
+func netrcCredentials(rawURL string) (string, string, error) {
+	path := os.Getenv(EnvNetrc)
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open netrc file: %w", err)
+	}
+	defer f.Close()
+
+	host := urlHost(rawURL)
+	if host == "" {
+		client := Client{URL: rawURL}
+		return "", "", client.resolveURL()
+	}
+
+	entries, err := parseNetrc(f)
+	if err != nil {
+		return "", "", err
+	}
+
+	// the first matching machine wins, default is only used as fallback
+	var fallback *netrcEntry
+	for i, entry := range entries {
+		if entry.machine == host {
+			return entry.login, entry.password, nil
+		}
+
+		if entry.isDefault && fallback == nil {
+			fallback = &entries[i]
+		}
+	}
+
+	if fallback != nil {
+		return fallback.login, fallback.password, nil
+	}
+
+	return "", "", nil
+}
+
+// urlHost returns the host of a rpc url, completed as WithURL does, or "" when
+// it is invalid.
+func urlHost(rawURL string) string {
+	client := Client{URL: rawURL}
+	if err := client.resolveURL(); err != nil {
+		return ""
+	}
+
+	u, err := url.Parse(client.URL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+type netrcEntry struct {
+	machine   string
+	login     string
+	password  string
+	isDefault bool
+}
+
+func parseNetrc(r io.Reader) ([]netrcEntry, error) {
+	var entries []netrcEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+
+	next := func() string {
+		if scanner.Scan() {
+			return scanner.Text()
+		}
+		return ""
+	}
+
+	for scanner.Scan() {
+		switch scanner.Text() {
+		case "machine":
+			entries = append(entries, netrcEntry{machine: next()})
+		case "default":
+			entries = append(entries, netrcEntry{isDefault: true})
+		case "login":
+			if login := next(); len(entries) > 0 {
+				entries[len(entries)-1].login = login
+			}
+		case "password":
+			if password := next(); len(entries) > 0 {
+				entries[len(entries)-1].password = password
+			}
+		case "account", "port":
+			next()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read netrc file: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,299 @@
+package transmission
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setEnv sets the given variables and returns a function restoring them.
+func setEnv(vars map[string]string) func() {
+	previous := make(map[string]*string)
+
+	for key, value := range vars {
+		if old, ok := os.LookupEnv(key); ok {
+			previous[key] = &old
+		} else {
+			previous[key] = nil
+		}
+		_ = os.Setenv(key, value)
+	}
+
+	return func() {
+		for key, value := range previous {
+			if value == nil {
+				_ = os.Unsetenv(key)
+			} else {
+				_ = os.Setenv(key, *value)
+			}
+		}
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+const yamlConfig = `
+default-profile: home
+profiles:
+  home:
+    url: nas.local
+    username: admin
+    password: home-secret
+    max-retries: 4
+  seedbox:
+    url: https://seedbox.example.com/transmission/rpc
+    username: seeder
+    password-file: /run/secrets/seedbox
+`
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transmission-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	yamlFile := writeFile(t, dir, "config.yaml", yamlConfig)
+	jsonFile := writeFile(t, dir, "config.json", `{
+		"profiles": {
+			"default": {"url": "http://10.0.0.2:9091/transmission/rpc", "username": "json"}
+		}
+	}`)
+
+	t.Run("should load the default profile of a yaml file", func(st *testing.T) {
+		config, err := LoadConfig(yamlFile, "")
+		assert.NoError(st, err)
+		assert.Equal(st, Config{URL: "nas.local", Username: "admin", Password: "home-secret", MaxRetries: 4}, config)
+	})
+
+	t.Run("should load a named profile", func(st *testing.T) {
+		config, err := LoadConfig(yamlFile, "seedbox")
+		assert.NoError(st, err)
+		assert.Equal(st, "seeder", config.Username)
+		assert.Equal(st, "/run/secrets/seedbox", config.PasswordFile)
+	})
+
+	t.Run("should fall back to the `default` profile of a json file", func(st *testing.T) {
+		config, err := LoadConfig(jsonFile, "")
+		assert.NoError(st, err)
+		assert.Equal(st, "json", config.Username)
+	})
+
+	t.Run("should fail with an unknown profile", func(st *testing.T) {
+		_, err := LoadConfig(yamlFile, "unknown")
+		assert.True(st, errors.Is(err, ErrProfileNotFound))
+	})
+
+	t.Run("should fail with a missing file", func(st *testing.T) {
+		_, err := LoadConfig(filepath.Join(dir, "missing.yaml"), "")
+		assert.Error(st, err)
+	})
+
+	t.Run("should fail with an invalid file", func(st *testing.T) {
+		_, err := LoadConfig(writeFile(st, dir, "invalid.json", "{"), "")
+		assert.Error(st, err)
+	})
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transmission-env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	passwordFile := writeFile(t, dir, "password", "file-secret\n")
+	configFile := writeFile(t, dir, "config.yaml", yamlConfig)
+	netrc := writeFile(t, dir, "netrc", `
+machine other.local login other password other-secret
+machine nas.local
+  login netrc-user
+  password netrc-secret
+default login anonymous password guest
+`)
+
+	clean := map[string]string{
+		EnvURL:          "",
+		EnvUsername:     "",
+		EnvPassword:     "",
+		EnvPasswordFile: "",
+		EnvConfigFile:   "",
+		EnvProfile:      "",
+		EnvNetrc:        netrc,
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected Config
+	}{
+		{
+			name:     "should read url and credentials from variables",
+			env:      map[string]string{EnvURL: "nas", EnvUsername: "user", EnvPassword: "secret"},
+			expected: Config{URL: "nas", Username: "user", Password: "secret"},
+		},
+		{
+			name: "should read password file from variables",
+			env:  map[string]string{EnvURL: "nas", EnvUsername: "user", EnvPasswordFile: passwordFile},
+			expected: Config{
+				URL:          "nas",
+				Username:     "user",
+				Password:     "file-secret",
+				PasswordFile: passwordFile,
+			},
+		},
+		{
+			name:     "should prefer password over password file",
+			env:      map[string]string{EnvUsername: "user", EnvPassword: "secret", EnvPasswordFile: passwordFile},
+			expected: Config{Username: "user", Password: "secret", PasswordFile: passwordFile},
+		},
+		{
+			name:     "should read config file profile",
+			env:      map[string]string{EnvConfigFile: configFile},
+			expected: Config{URL: "nas.local", Username: "admin", Password: "home-secret", MaxRetries: 4},
+		},
+		{
+			name:     "should override config file values with variables",
+			env:      map[string]string{EnvConfigFile: configFile, EnvURL: "nas:9092", EnvPassword: "env-secret"},
+			expected: Config{URL: "nas:9092", Password: "env-secret", MaxRetries: 4},
+		},
+		{
+			name:     "should keep config file credentials for the same host",
+			env:      map[string]string{EnvConfigFile: configFile, EnvURL: "http://nas.local:9092"},
+			expected: Config{URL: "http://nas.local:9092", Username: "admin", Password: "home-secret", MaxRetries: 4},
+		},
+		{
+			name:     "should not send config file credentials to another host",
+			env:      map[string]string{EnvConfigFile: configFile, EnvURL: "other.local"},
+			expected: Config{URL: "other.local", Username: "other", Password: "other-secret", MaxRetries: 4},
+		},
+		{
+			name: "should not shadow a password file variable with config file password",
+			env:  map[string]string{EnvConfigFile: configFile, EnvPasswordFile: passwordFile},
+			expected: Config{
+				URL:          "nas.local",
+				Username:     "admin",
+				Password:     "file-secret",
+				PasswordFile: passwordFile,
+				MaxRetries:   4,
+			},
+		},
+		{
+			name:     "should use netrc credentials matching the url host",
+			env:      map[string]string{EnvURL: "http://nas.local:9091/transmission/rpc"},
+			expected: Config{URL: "http://nas.local:9091/transmission/rpc", Username: "netrc-user", Password: "netrc-secret"},
+		},
+		{
+			name:     "should use netrc default entry",
+			env:      map[string]string{EnvURL: "unknown.local"},
+			expected: Config{URL: "unknown.local", Username: "anonymous", Password: "guest"},
+		},
+		{
+			name:     "should ignore netrc when credentials are given",
+			env:      map[string]string{EnvURL: "nas.local", EnvUsername: "user"},
+			expected: Config{URL: "nas.local", Username: "user"},
+		},
+		{
+			name:     "should ignore a missing netrc file",
+			env:      map[string]string{EnvURL: "nas.local", EnvNetrc: filepath.Join(dir, "missing")},
+			expected: Config{URL: "nas.local"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			restore := setEnv(clean)
+			defer restore()
+			// nolint
+			defer setEnv(test.env)()
+
+			config, err := ConfigFromEnv()
+			assert.NoError(st, err)
+			// nolint
+			assert.Equal(st, test.expected, config)
+		})
+	}
+
+	t.Run("should fail with a missing password file", func(st *testing.T) {
+		restore := setEnv(clean)
+		defer restore()
+		defer setEnv(map[string]string{EnvPasswordFile: filepath.Join(dir, "missing")})()
+
+		_, err := ConfigFromEnv()
+		assert.Error(st, err)
+	})
+
+	t.Run("should fail with a missing profile", func(st *testing.T) {
+		restore := setEnv(clean)
+		defer restore()
+		defer setEnv(map[string]string{EnvConfigFile: configFile, EnvProfile: "unknown"})()
+
+		_, err := ConfigFromEnv()
+		assert.True(st, errors.Is(err, ErrProfileNotFound))
+	})
+}
+
+func TestNewFromEnv(t *testing.T) {
+	restore := setEnv(map[string]string{
+		EnvURL:          "nas.local",
+		EnvUsername:     "user",
+		EnvPassword:     "secret",
+		EnvPasswordFile: "",
+		EnvConfigFile:   "",
+	})
+	defer restore()
+
+	t.Run("should create a client from the environment", func(st *testing.T) {
+		client, err := NewFromEnv()
+		assert.NoError(st, err)
+		assert.Equal(st, "http://nas.local:9091/transmission/rpc", client.URL)
+		assert.Equal(st, "user", client.Username)
+		assert.Equal(st, "secret", client.Password)
+	})
+
+	t.Run("should give precedence to explicit options", func(st *testing.T) {
+		client, err := NewFromEnv(WithURL("https://other.local/rpc"), WithBasicAuth("admin", "admin"))
+		assert.NoError(st, err)
+		assert.Equal(st, "https://other.local/rpc", client.URL)
+		assert.Equal(st, "admin", client.Username)
+	})
+}
+
+func TestConfig_Options(t *testing.T) {
+	t.Run("should read password file", func(st *testing.T) {
+		dir, err := ioutil.TempDir("", "transmission-options")
+		if err != nil {
+			st.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		client, err := NewFromConfig(Config{
+			URL:          "nas",
+			Username:     "user",
+			PasswordFile: writeFile(st, dir, "password", "from-file\r\n"),
+			MaxRetries:   3,
+			ServerName:   "nas.local",
+		})
+		assert.NoError(st, err)
+		assert.Equal(st, "from-file", client.Password)
+		assert.Equal(st, 3, client.MaxRetries)
+		assert.Equal(st, "https://nas:9091/transmission/rpc", client.URL)
+	})
+
+	t.Run("should fail with a missing password file", func(st *testing.T) {
+		client, err := NewFromConfig(Config{PasswordFile: "missing"})
+		assert.Nil(st, client)
+		assert.Error(st, err)
+	})
+}
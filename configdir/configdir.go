@@ -0,0 +1,130 @@
+// Package configdir reads the files of a daemon config dir (Session.ConfigDir),
+// e.g. to audit a stopped daemon, and writes its settings before it starts.
+package configdir
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/mfuentesg/transmission"
+)
+
+const (
+	SettingsFile = "settings.json"
+	StatsFile    = "stats.json"
+	ResumeDir    = "resume"
+)
+
+var (
+	ErrInvalidSettings = errors.New("invalid settings")
+)
+
+// settingsKeys are the settings.json keys named differently in the session
+// rpc arguments.
+var settingsKeys = map[string]string{
+	"ratio-limit":         "seedRatioLimit",
+	"ratio-limit-enabled": "seedRatioLimited",
+}
+
+// encryptionModes are the rpc names of the settings.json encryption values.
+var encryptionModes = []string{"tolerated", "preferred", "required"}
+
+// LoadSettings reads the settings.json of the config dir into the fields of
+// Session, ConfigDir being set to dir.
+func LoadSettings(dir string) (transmission.Session, error) {
+	var session transmission.Session
+
+	settings, err := readSettings(filepath.Join(dir, SettingsFile))
+	if err != nil {
+		return session, err
+	}
+
+	if err := remarshal(toSession(settings), &session); err != nil {
+		return session, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+
+	session.ConfigDir = dir
+
+	return session, nil
+}
+
+// LoadStats reads the cumulative stats of the stats.json of the config dir.
+func LoadStats(dir string) (transmission.CumulativeStats, error) {
+	var stats struct {
+		DownloadedBytes int64 `json:"downloaded-bytes"`
+		FilesAdded      int64 `json:"files-added"`
+		SecondsActive   int64 `json:"seconds-active"`
+		SessionCount    int64 `json:"session-count"`
+		UploadedBytes   int64 `json:"uploaded-bytes"`
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, StatsFile))
+	if err != nil {
+		return transmission.CumulativeStats{}, err
+	}
+
+	if err := json.Unmarshal(buf, &stats); err != nil {
+		return transmission.CumulativeStats{}, fmt.Errorf("%w: %s: %v", ErrInvalidSettings, StatsFile, err)
+	}
+
+	return transmission.CumulativeStats{
+		UploadedBytes:   stats.UploadedBytes,
+		DownloadedBytes: stats.DownloadedBytes,
+		FilesAdded:      stats.FilesAdded,
+		SessionCount:    stats.SessionCount,
+		SecondsActive:   stats.SecondsActive,
+	}, nil
+}
+
+// readSettings returns the values of a settings.json file.
+func readSettings(path string) (map[string]interface{}, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// numbers are kept as written when merging the file
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+
+	var settings map[string]interface{}
+	if err := decoder.Decode(&settings); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSettings, filepath.Base(path), err)
+	}
+
+	return settings, nil
+}
+
+// toSession renames the settings.json values to their rpc names.
+func toSession(settings map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(settings))
+
+	for key, value := range settings {
+		if name, ok := settingsKeys[key]; ok {
+			key = name
+		}
+		values[key] = value
+	}
+
+	if mode, ok := values["encryption"].(json.Number); ok {
+		delete(values, "encryption")
+		if i, err := mode.Int64(); err == nil && i >= 0 && i < int64(len(encryptionModes)) {
+			values["encryption"] = encryptionModes[i]
+		}
+	}
+
+	return values
+}
+
+func remarshal(value, target interface{}) error {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(buf, target)
+}
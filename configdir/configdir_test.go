@@ -0,0 +1,121 @@
+package configdir
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+const settingsJSON = `{
+    "alt-speed-down": 50,
+    "alt-speed-enabled": true,
+    "blocklist-url": "http://www.example.com/blocklist",
+    "cache-size-mb": 4,
+    "dht-enabled": true,
+    "download-dir": "/var/lib/transmission/downloads",
+    "download-queue-size": 5,
+    "encryption": 2,
+    "incomplete-dir-enabled": false,
+    "peer-port": 51413,
+    "ratio-limit": 2.5,
+    "ratio-limit-enabled": true,
+    "rpc-whitelist": "127.0.0.1",
+    "speed-limit-up": 100,
+    "speed-limit-up-enabled": true,
+    "umask": 18
+}`
+
+func tempDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "configdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLoadSettings(t *testing.T) {
+	t.Run("should map the settings onto the session fields", func(st *testing.T) {
+		dir := tempDir(st, map[string]string{SettingsFile: settingsJSON})
+		defer os.RemoveAll(dir)
+
+		session, err := LoadSettings(dir)
+		assert.NoError(st, err)
+		assert.Equal(st, transmission.Session{
+			AltSpeedDown:        50,
+			AltSpeedEnabled:     true,
+			BlockListURL:        "http://www.example.com/blocklist",
+			CacheSizeMb:         4,
+			ConfigDir:           dir,
+			DhtEnabled:          true,
+			DownloadDir:         "/var/lib/transmission/downloads",
+			DownloadQueueSize:   5,
+			Encryption:          "required",
+			PeerPort:            51413,
+			SeedRatioLimit:      2.5,
+			SeedRatioLimited:    true,
+			SpeedLimitUp:        100,
+			SpeedLimitUpEnabled: true,
+		}, session)
+	})
+
+	t.Run("should fail for missing or invalid files", func(st *testing.T) {
+		dir := tempDir(st, nil)
+		defer os.RemoveAll(dir)
+
+		_, err := LoadSettings(dir)
+		assert.True(st, os.IsNotExist(err))
+
+		assert.NoError(st, ioutil.WriteFile(filepath.Join(dir, SettingsFile), []byte("{"), 0600))
+		_, err = LoadSettings(dir)
+		assert.True(st, errors.Is(err, ErrInvalidSettings))
+
+		assert.NoError(st, ioutil.WriteFile(filepath.Join(dir, SettingsFile), []byte(`{"peer-port": "x"}`), 0600))
+		_, err = LoadSettings(dir)
+		assert.True(st, errors.Is(err, ErrInvalidSettings))
+	})
+}
+
+func TestLoadStats(t *testing.T) {
+	dir := tempDir(t, map[string]string{StatsFile: `{
+    "downloaded-bytes": 1024,
+    "files-added": 3,
+    "seconds-active": 3600,
+    "session-count": 7,
+    "uploaded-bytes": 2048
+}`})
+	defer os.RemoveAll(dir)
+
+	t.Run("should map the stats onto the cumulative stats", func(st *testing.T) {
+		stats, err := LoadStats(dir)
+		assert.NoError(st, err)
+		assert.Equal(st, transmission.CumulativeStats{
+			UploadedBytes:   2048,
+			DownloadedBytes: 1024,
+			FilesAdded:      3,
+			SessionCount:    7,
+			SecondsActive:   3600,
+		}, stats)
+	})
+
+	t.Run("should fail for invalid files", func(st *testing.T) {
+		assert.NoError(st, ioutil.WriteFile(filepath.Join(dir, StatsFile), []byte("[]"), 0600))
+		_, err := LoadStats(dir)
+		assert.True(st, errors.Is(err, ErrInvalidSettings))
+	})
+}
@@ -0,0 +1,238 @@
+package configdir
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/bencode"
+)
+
+const (
+	// BlockSize is the unit of the Progress bitfield.
+	BlockSize = 16 << 10
+)
+
+var (
+	ErrInvalidResume = errors.New("invalid resume file")
+)
+
+// Resume is the state the daemon keeps for a torrent in the resume dir. The
+// fields shared with the rpc are set on the embedded Torrent, which has no ID
+// since ids are assigned when the daemon starts, nor Status, Paused telling
+// whether the torrent will start.
+type Resume struct {
+	transmission.Torrent
+	IncompleteDir string
+	Paused        bool
+	Progress      Progress
+}
+
+// Progress is the data stored for the torrent. Bitfield holds one bit per
+// BlockSize block, unless all or none of them are stored.
+type Progress struct {
+	Complete bool
+	Bitfield []byte
+}
+
+// HasBlock reports whether the block at index is stored.
+func (p Progress) HasBlock(index int) bool {
+	if p.Complete {
+		return true
+	}
+
+	if index < 0 || index/8 >= len(p.Bitfield) {
+		return false
+	}
+
+	return p.Bitfield[index/8]&(0x80>>(index%8)) != 0
+}
+
+type rawResume struct {
+	Name               string        `bencode:"name"`
+	Destination        string        `bencode:"destination"`
+	IncompleteDir      string        `bencode:"incomplete-dir"`
+	AddedDate          int64         `bencode:"added-date"`
+	DoneDate           int64         `bencode:"done-date"`
+	ActivityDate       int64         `bencode:"activity-date"`
+	Downloaded         int64         `bencode:"downloaded"`
+	Uploaded           int64         `bencode:"uploaded"`
+	Corrupt            int64         `bencode:"corrupt"`
+	Paused             bool          `bencode:"paused"`
+	Labels             []string      `bencode:"labels"`
+	BandwidthPriority  int64         `bencode:"bandwidth-priority"`
+	MaxPeers           int64         `bencode:"max-peers"`
+	SecondsDownloading int64         `bencode:"seconds-downloading"`
+	SecondsSeeding     int64         `bencode:"seconds-seeding"`
+	RatioLimit         rawRatioLimit `bencode:"ratio-limit"`
+	IdleLimit          rawIdleLimit  `bencode:"idle-limit"`
+	SpeedLimitDown     rawSpeedLimit `bencode:"speed-limit-down"`
+	SpeedLimitUp       rawSpeedLimit `bencode:"speed-limit-up"`
+	Progress           rawProgress   `bencode:"progress"`
+}
+
+type rawRatioLimit struct {
+	// a string such as "2.000000", bencode having no floats
+	RatioLimit interface{} `bencode:"ratio-limit"`
+	RatioMode  int64       `bencode:"ratio-mode"`
+}
+
+type rawIdleLimit struct {
+	IdleLimit int64 `bencode:"idle-limit"`
+	IdleMode  int64 `bencode:"idle-mode"`
+}
+
+type rawSpeedLimit struct {
+	SpeedBps            int64 `bencode:"speed-Bps"`
+	UseGlobalSpeedLimit bool  `bencode:"use-global-speed-limit"`
+	UseSpeedLimit       bool  `bencode:"use-speed-limit"`
+}
+
+type rawProgress struct {
+	// "all", "none" or the bitfield
+	Blocks []byte `bencode:"blocks"`
+	// before 2.20
+	Have     string `bencode:"have"`
+	Bitfield []byte `bencode:"bitfield"`
+}
+
+// LoadResume reads a .resume file. The hash is taken from the file name,
+// <hash>.resume since 4.0.
+func LoadResume(path string) (Resume, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Resume{}, err
+	}
+
+	r, err := ParseResume(buf)
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	r.HashString = resumeHash(filepath.Base(path))
+
+	return r, nil
+}
+
+// LoadResumes reads all the .resume files of the config dir, sorted by file
+// name.
+func LoadResumes(dir string) ([]Resume, error) {
+	if _, err := os.Stat(filepath.Join(dir, ResumeDir)); err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, ResumeDir, "*.resume"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+
+	resumes := make([]Resume, 0, len(paths))
+	for _, path := range paths {
+		r, err := LoadResume(path)
+		if err != nil {
+			return nil, err
+		}
+		resumes = append(resumes, r)
+	}
+
+	return resumes, nil
+}
+
+// ParseResume parses the content of a .resume file.
+func ParseResume(data []byte) (Resume, error) {
+	var raw rawResume
+	if err := bencode.Unmarshal(data, &raw); err != nil {
+		return Resume{}, fmt.Errorf("%w: %v", ErrInvalidResume, err)
+	}
+
+	ratio, err := ratioLimit(raw.RatioLimit.RatioLimit)
+	if err != nil {
+		return Resume{}, err
+	}
+
+	r := Resume{
+		Torrent: transmission.Torrent{
+			Name:                raw.Name,
+			DownloadDir:         raw.Destination,
+			AddedDate:           raw.AddedDate,
+			DoneDate:            raw.DoneDate,
+			ActivityDate:        raw.ActivityDate,
+			DownloadedEver:      raw.Downloaded,
+			UploadedEver:        raw.Uploaded,
+			CorruptEver:         raw.Corrupt,
+			Labels:              raw.Labels,
+			BandwidthPriority:   raw.BandwidthPriority,
+			PeerLimit:           raw.MaxPeers,
+			SecondsDownloading:  raw.SecondsDownloading,
+			SecondsSeeding:      raw.SecondsSeeding,
+			SeedRatioLimit:      ratio,
+			SeedRatioMode:       raw.RatioLimit.RatioMode,
+			SeedIdleLimit:       raw.IdleLimit.IdleLimit,
+			SeedIdleMode:        raw.IdleLimit.IdleMode,
+			DownloadLimit:       raw.SpeedLimitDown.SpeedBps / 1000,
+			DownloadLimited:     raw.SpeedLimitDown.UseSpeedLimit,
+			UploadLimit:         raw.SpeedLimitUp.SpeedBps / 1000,
+			UploadLimited:       raw.SpeedLimitUp.UseSpeedLimit,
+			HonorsSessionLimits: raw.SpeedLimitDown.UseGlobalSpeedLimit || raw.SpeedLimitUp.UseGlobalSpeedLimit,
+		},
+		IncompleteDir: raw.IncompleteDir,
+		Paused:        raw.Paused,
+		Progress:      progress(raw.Progress),
+	}
+
+	if r.Progress.Complete {
+		r.PercentDone = 1
+	}
+
+	return r, nil
+}
+
+func ratioLimit(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return float64(v), nil
+	case string:
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: ratio limit %q", ErrInvalidResume, v)
+		}
+		return ratio, nil
+	default:
+		return 0, fmt.Errorf("%w: ratio limit %v", ErrInvalidResume, v)
+	}
+}
+
+func progress(raw rawProgress) Progress {
+	switch {
+	case string(raw.Blocks) == "all" || raw.Have == "all":
+		return Progress{Complete: true}
+	case string(raw.Blocks) == "none":
+		return Progress{}
+	case len(raw.Blocks) > 0:
+		return Progress{Bitfield: raw.Blocks}
+	default:
+		return Progress{Bitfield: raw.Bitfield}
+	}
+}
+
+// resumeHash returns the hash of <hash>.resume file names, or "" for the
+// <name>.<hash prefix>.resume ones of older daemons.
+func resumeHash(name string) string {
+	hash := strings.TrimSuffix(name, ".resume")
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != 40 {
+		return ""
+	}
+
+	return strings.ToLower(hash)
+}
@@ -0,0 +1,148 @@
+package configdir
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfuentesg/transmission/bencode"
+	"github.com/stretchr/testify/assert"
+)
+
+const hash = "0123456789abcdef0123456789abcdef01234567"
+
+func resume(t *testing.T, values map[string]interface{}) string {
+	base := map[string]interface{}{
+		"name":                "debian.iso",
+		"destination":         "/data",
+		"incomplete-dir":      "/incomplete",
+		"added-date":          1600000000,
+		"done-date":           1600003600,
+		"activity-date":       1600007200,
+		"downloaded":          1000,
+		"uploaded":            3000,
+		"corrupt":             16384,
+		"paused":              1,
+		"labels":              []string{"linux", "iso"},
+		"bandwidth-priority":  1,
+		"max-peers":           50,
+		"seconds-downloading": 60,
+		"seconds-seeding":     120,
+		"ratio-limit":         map[string]interface{}{"ratio-limit": "2.500000", "ratio-mode": 1},
+		"idle-limit":          map[string]interface{}{"idle-limit": 30, "idle-mode": 2},
+		"speed-limit-down":    map[string]interface{}{"speed-Bps": 100000, "use-global-speed-limit": 1, "use-speed-limit": 1},
+		"speed-limit-up":      map[string]interface{}{"speed-Bps": 50000, "use-global-speed-limit": 1, "use-speed-limit": 0},
+		"progress":            map[string]interface{}{"blocks": "all", "mtimes": []int{1600003600}},
+		"peers2":              "ignored",
+	}
+
+	for key, value := range values {
+		base[key] = value
+	}
+
+	buf, err := bencode.Marshal(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf)
+}
+
+func TestLoadResume(t *testing.T) {
+	t.Run("should map the resume onto the torrent fields", func(st *testing.T) {
+		dir := tempDir(st, map[string]string{"resume/" + hash + ".resume": resume(st, nil)})
+		defer os.RemoveAll(dir)
+
+		r, err := LoadResume(filepath.Join(dir, ResumeDir, hash+".resume"))
+		assert.NoError(st, err)
+		assert.Equal(st, hash, r.HashString)
+		assert.Equal(st, "debian.iso", r.Name)
+		assert.Equal(st, "/data", r.DownloadDir)
+		assert.Equal(st, "/incomplete", r.IncompleteDir)
+		assert.Equal(st, int64(1600000000), r.AddedDate)
+		assert.Equal(st, int64(1600003600), r.DoneDate)
+		assert.Equal(st, int64(1600007200), r.ActivityDate)
+		assert.Equal(st, int64(1000), r.DownloadedEver)
+		assert.Equal(st, int64(3000), r.UploadedEver)
+		assert.Equal(st, int64(16384), r.CorruptEver)
+		assert.True(st, r.Paused)
+		assert.Equal(st, []string{"linux", "iso"}, r.Labels)
+		assert.Equal(st, int64(1), r.BandwidthPriority)
+		assert.Equal(st, int64(50), r.PeerLimit)
+		assert.Equal(st, int64(60), r.SecondsDownloading)
+		assert.Equal(st, int64(120), r.SecondsSeeding)
+		assert.Equal(st, 2.5, r.SeedRatioLimit)
+		assert.Equal(st, int64(1), r.SeedRatioMode)
+		assert.Equal(st, int64(30), r.SeedIdleLimit)
+		assert.Equal(st, int64(2), r.SeedIdleMode)
+		assert.Equal(st, int64(100), r.DownloadLimit)
+		assert.True(st, r.DownloadLimited)
+		assert.Equal(st, int64(50), r.UploadLimit)
+		assert.False(st, r.UploadLimited)
+		assert.True(st, r.HonorsSessionLimits)
+		assert.True(st, r.Progress.Complete)
+		assert.Equal(st, 1.0, r.PercentDone)
+	})
+
+	t.Run("should read the progress bitfield", func(st *testing.T) {
+		r, err := ParseResume([]byte(resume(st, map[string]interface{}{
+			"progress": map[string]interface{}{"blocks": []byte{0xa0}},
+		})))
+		assert.NoError(st, err)
+		assert.False(st, r.Progress.Complete)
+		assert.Equal(st, []bool{true, false, true, false}, []bool{
+			r.Progress.HasBlock(0), r.Progress.HasBlock(1), r.Progress.HasBlock(2), r.Progress.HasBlock(9),
+		})
+		assert.Equal(st, 0.0, r.PercentDone)
+
+		r, err = ParseResume([]byte(resume(st, map[string]interface{}{
+			"progress": map[string]interface{}{"blocks": "none"},
+		})))
+		assert.NoError(st, err)
+		assert.False(st, r.Progress.HasBlock(0))
+
+		r, err = ParseResume([]byte(resume(st, map[string]interface{}{
+			"progress": map[string]interface{}{"have": "all"},
+		})))
+		assert.NoError(st, err)
+		assert.True(st, r.Progress.Complete)
+	})
+
+	t.Run("should reject invalid resume files", func(st *testing.T) {
+		for _, data := range []string{
+			"<html>",
+			resume(st, map[string]interface{}{"labels": "linux"}),
+			resume(st, map[string]interface{}{"ratio-limit": map[string]interface{}{"ratio-limit": "high"}}),
+		} {
+			_, err := ParseResume([]byte(data))
+			assert.True(st, errors.Is(err, ErrInvalidResume), "%q", data)
+		}
+	})
+}
+
+func TestLoadResumes(t *testing.T) {
+	t.Run("should read all the resume files", func(st *testing.T) {
+		dir := tempDir(st, map[string]string{
+			"resume/" + hash + ".resume":                resume(st, nil),
+			"resume/ubuntu.iso.0123456789abcdef.resume": resume(st, map[string]interface{}{"name": "ubuntu.iso"}),
+			"resume/notes.txt":                          "ignored",
+		})
+		defer os.RemoveAll(dir)
+
+		resumes, err := LoadResumes(dir)
+		assert.NoError(st, err)
+		assert.Len(st, resumes, 2)
+		assert.Equal(st, hash, resumes[0].HashString)
+		assert.Equal(st, "ubuntu.iso", resumes[1].Name)
+		assert.Equal(st, "", resumes[1].HashString)
+	})
+
+	t.Run("should fail without resume dir", func(st *testing.T) {
+		dir := tempDir(st, nil)
+		defer os.RemoveAll(dir)
+
+		_, err := LoadResumes(dir)
+		assert.True(st, os.IsNotExist(err))
+	})
+}
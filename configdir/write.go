@@ -0,0 +1,219 @@
+package configdir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfuentesg/transmission"
+)
+
+const (
+	saltLength = 8
+	saltChars  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789./"
+)
+
+// Settings are the values WriteSettings merges into settings.json: the ones
+// SessionSet changes on a running daemon, and the rpc ones only read from the
+// file. As with SessionSet, zero values are not written, Disabled lists the
+// booleans to set to false.
+type Settings struct {
+	transmission.SessionSet
+	RPCEnabled                bool   `json:"rpc-enabled,omitempty"`
+	RPCBindAddress            string `json:"rpc-bind-address,omitempty"`
+	RPCPort                   int64  `json:"rpc-port,omitempty"`
+	RPCURL                    string `json:"rpc-url,omitempty"`
+	RPCAuthenticationRequired bool   `json:"rpc-authentication-required,omitempty"`
+	RPCUsername               string `json:"rpc-username,omitempty"`
+	// RPCPassword is hashed as the daemon does, unless it already is.
+	RPCPassword             string   `json:"-"`
+	RPCWhitelistEnabled     bool     `json:"rpc-whitelist-enabled,omitempty"`
+	RPCWhitelist            []string `json:"-"`
+	RPCHostWhitelistEnabled bool     `json:"rpc-host-whitelist-enabled,omitempty"`
+	RPCHostWhitelist        []string `json:"-"`
+	// Disabled are the json names of the booleans to set to false, e.g.
+	// "dht-enabled" or "seedRatioLimited".
+	Disabled []string `json:"-"`
+}
+
+// WriteSettings merges settings into the settings.json of the config dir,
+// keeping the other values, and replaces the file atomically. The daemon
+// must be stopped, as it writes its own settings when exiting.
+func WriteSettings(dir string, settings Settings) error {
+	path := filepath.Join(dir, SettingsFile)
+
+	values, err := readSettings(path)
+	if os.IsNotExist(err) {
+		values = make(map[string]interface{})
+	} else if err != nil {
+		return err
+	}
+
+	changes, err := settings.values()
+	if err != nil {
+		return err
+	}
+
+	for key, value := range changes {
+		values[key] = value
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+
+	if err := encoder.Encode(values); err != nil {
+		return err
+	}
+
+	return writeFile(path, buf.Bytes())
+}
+
+// values returns the settings.json values of s.
+func (s Settings) values() (map[string]interface{}, error) {
+	var values map[string]interface{}
+	if err := remarshal(s, &values); err != nil {
+		return nil, err
+	}
+
+	for _, key := range s.Disabled {
+		values[key] = false
+	}
+
+	values = fromSession(values)
+
+	if s.RPCPassword != "" {
+		password := s.RPCPassword
+		if !isPasswordHash(password) {
+			var err error
+			if password, err = HashPassword(password); err != nil {
+				return nil, err
+			}
+		}
+		values["rpc-password"] = password
+	}
+
+	if len(s.RPCWhitelist) > 0 {
+		values["rpc-whitelist"] = strings.Join(s.RPCWhitelist, ",")
+	}
+
+	if len(s.RPCHostWhitelist) > 0 {
+		values["rpc-host-whitelist"] = strings.Join(s.RPCHostWhitelist, ",")
+	}
+
+	return values, nil
+}
+
+// fromSession renames the rpc values to their settings.json names, the
+// reverse of toSession.
+func fromSession(session map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(session))
+
+	for key, value := range session {
+		for name, rpcName := range settingsKeys {
+			if key == rpcName {
+				key = name
+			}
+		}
+		values[key] = value
+	}
+
+	if mode, ok := values["encryption"].(string); ok {
+		delete(values, "encryption")
+		for i, name := range encryptionModes {
+			if name == mode {
+				values["encryption"] = i
+			}
+		}
+	}
+
+	return values
+}
+
+// HashPassword hashes an rpc password as the daemon stores it in
+// settings.json: "{", the hex SHA-1 of the password followed by a random salt,
+// and the salt.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	for i := range salt {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(saltChars))))
+		if err != nil {
+			return "", err
+		}
+		salt[i] = saltChars[n.Int64()]
+	}
+
+	return hashPassword(password, string(salt)), nil
+}
+
+// CheckPassword reports whether password matches a hash of HashPassword.
+func CheckPassword(hash, password string) bool {
+	if !isPasswordHash(hash) {
+		return false
+	}
+
+	return hashPassword(password, hash[len(hash)-saltLength:]) == hash
+}
+
+func hashPassword(password, salt string) string {
+	sum := sha1.Sum([]byte(password + salt))
+	return "{" + hex.EncodeToString(sum[:]) + salt
+}
+
+func isPasswordHash(password string) bool {
+	if len(password) != 1+hex.EncodedLen(sha1.Size)+saltLength || password[0] != '{' {
+		return false
+	}
+
+	_, err := hex.DecodeString(password[1 : 1+hex.EncodedLen(sha1.Size)])
+	return err == nil
+}
+
+// writeFile replaces path with data through a temporary file renamed over
+// it, so readers never see a partial file. The mode of the existing file is
+// kept.
+func writeFile(path string, data []byte) error {
+	mode := os.FileMode(0600)
+	if stat, err := os.Stat(path); err == nil {
+		mode = stat.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package configdir
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSettings(t *testing.T) {
+	read := func(t *testing.T, dir string) map[string]interface{} {
+		buf, err := ioutil.ReadFile(filepath.Join(dir, SettingsFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var values map[string]interface{}
+		if err := json.Unmarshal(buf, &values); err != nil {
+			t.Fatal(err)
+		}
+
+		return values
+	}
+
+	t.Run("should merge the settings keeping the other values", func(st *testing.T) {
+		dir := tempDir(st, map[string]string{SettingsFile: settingsJSON})
+		defer os.RemoveAll(dir)
+		assert.NoError(st, os.Chmod(filepath.Join(dir, SettingsFile), 0640))
+
+		err := WriteSettings(dir, Settings{
+			SessionSet: transmission.SessionSet{
+				DownloadDir:      "/srv/<downloads>",
+				Encryption:       "preferred",
+				SeedRatioLimit:   1.5,
+				SeedRatioLimited: true,
+				PexEnabled:       true,
+			},
+			RPCEnabled:              true,
+			RPCPort:                 9092,
+			RPCWhitelistEnabled:     true,
+			RPCWhitelist:            []string{"127.0.0.1", "192.168.*.*"},
+			RPCHostWhitelist:        []string{"seedbox.example.com"},
+			RPCUsername:             "admin",
+			RPCPassword:             "secret",
+			Disabled:                []string{"dht-enabled", "speed-limit-up-enabled"},
+			RPCHostWhitelistEnabled: true,
+		})
+		assert.NoError(st, err)
+
+		values := read(st, dir)
+		password, _ := values["rpc-password"].(string)
+		delete(values, "rpc-password")
+
+		assert.True(st, CheckPassword(password, "secret"))
+		assert.False(st, CheckPassword(password, "wrong"))
+		assert.Equal(st, map[string]interface{}{
+			"alt-speed-down":             50.0,
+			"alt-speed-enabled":          true,
+			"blocklist-url":              "http://www.example.com/blocklist",
+			"cache-size-mb":              4.0,
+			"dht-enabled":                false,
+			"download-dir":               "/srv/<downloads>",
+			"download-queue-size":        5.0,
+			"encryption":                 1.0,
+			"incomplete-dir-enabled":     false,
+			"peer-port":                  51413.0,
+			"pex-enabled":                true,
+			"ratio-limit":                1.5,
+			"ratio-limit-enabled":        true,
+			"rpc-enabled":                true,
+			"rpc-host-whitelist":         "seedbox.example.com",
+			"rpc-host-whitelist-enabled": true,
+			"rpc-port":                   9092.0,
+			"rpc-username":               "admin",
+			"rpc-whitelist":              "127.0.0.1,192.168.*.*",
+			"rpc-whitelist-enabled":      true,
+			"speed-limit-up":             100.0,
+			"speed-limit-up-enabled":     false,
+			"umask":                      18.0,
+		}, values)
+
+		buf, _ := ioutil.ReadFile(filepath.Join(dir, SettingsFile))
+		assert.True(st, strings.HasPrefix(string(buf), "{\n    \"alt-speed-down\": 50,\n"))
+
+		stat, err := os.Stat(filepath.Join(dir, SettingsFile))
+		assert.NoError(st, err)
+		assert.Equal(st, os.FileMode(0640), stat.Mode().Perm())
+
+		files, _ := ioutil.ReadDir(dir)
+		assert.Len(st, files, 1)
+
+		session, err := LoadSettings(dir)
+		assert.NoError(st, err)
+		assert.Equal(st, "preferred", session.Encryption)
+		assert.Equal(st, 1.5, session.SeedRatioLimit)
+	})
+
+	t.Run("should create the file", func(st *testing.T) {
+		dir := tempDir(st, nil)
+		defer os.RemoveAll(dir)
+
+		hash := hashPassword("secret", "abcdefgh")
+		assert.NoError(st, WriteSettings(dir, Settings{RPCPassword: hash}))
+		assert.Equal(st, map[string]interface{}{"rpc-password": hash}, read(st, dir))
+
+		stat, err := os.Stat(filepath.Join(dir, SettingsFile))
+		assert.NoError(st, err)
+		assert.Equal(st, os.FileMode(0600), stat.Mode().Perm())
+	})
+
+	t.Run("should not replace invalid files", func(st *testing.T) {
+		dir := tempDir(st, map[string]string{SettingsFile: "{"})
+		defer os.RemoveAll(dir)
+
+		assert.Error(st, WriteSettings(dir, Settings{RPCPort: 9091}))
+
+		buf, _ := ioutil.ReadFile(filepath.Join(dir, SettingsFile))
+		assert.Equal(st, "{", string(buf))
+	})
+}
+
+func TestHashPassword(t *testing.T) {
+	t.Run("should hash as the daemon", func(st *testing.T) {
+		// sha1("secret" + "abcdefgh")
+		assert.Equal(st, "{49674dc08f73aca066468e521b49af350b696678abcdefgh", hashPassword("secret", "abcdefgh"))
+	})
+
+	t.Run("should use a random salt", func(st *testing.T) {
+		first, err := HashPassword("secret")
+		assert.NoError(st, err)
+		second, err := HashPassword("secret")
+		assert.NoError(st, err)
+
+		assert.Len(st, first, 49)
+		assert.NotEqual(st, first, second)
+		assert.True(st, CheckPassword(first, "secret"))
+		assert.True(st, CheckPassword(second, "secret"))
+		assert.False(st, CheckPassword("secret", "secret"))
+	})
+}
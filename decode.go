@@ -0,0 +1,455 @@
+package transmission
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type DecodeMode int
+
+const (
+	// DecodeDefault decodes responses with encoding/json, NumBool fields
+	// treat any unexpected value as false.
+	DecodeDefault DecodeMode = iota
+	// DecodeStrict additionally reports malformed values, e.g. a NumBool
+	// field which is neither a boolean nor 0/1, with a *DecodeError.
+	DecodeStrict
+	// DecodeLenient never fails on field values: compatible representations
+	// sent by other daemon versions (bools as 0/1, numbers as strings, null
+	// lists) are coerced, and the values which can not be are left empty.
+	// Both are reported as DecodeWarning to the WithDecodeWarnings handler.
+	DecodeLenient
+)
+
+var (
+	ErrMalformedValue = errors.New("malformed value")
+)
+
+// DecodeError describes a response value rejected by DecodeStrict. Field is
+// the json path of the value, e.g. "[0].trackerStats[1].lastScrapeTimedOut".
+type DecodeError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("invalid value %s for field %q: %v", e.Value, e.Field, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeWarning describes a value coerced or dropped by DecodeLenient.
+type DecodeWarning struct {
+	Method  Method
+	Field   string
+	Value   string
+	Message string
+}
+
+func (w DecodeWarning) String() string {
+	return fmt.Sprintf("%s: field %q with value %s: %s", w.Method, w.Field, w.Value, w.Message)
+}
+
+// WithDecodeMode selects how responses are decoded, DecodeDefault when not
+// set.
+func WithDecodeMode(mode DecodeMode) Option {
+	return func(c *Client) {
+		c.decodeMode = mode
+	}
+}
+
+// WithDecodeWarnings sets the function receiving the warnings of each
+// response decoded with DecodeLenient.
+func WithDecodeWarnings(handler func(warnings []DecodeWarning)) Option {
+	return func(c *Client) {
+		c.warningHandler = handler
+	}
+}
+
+// decode fills target with base, a value taken from the response arguments
+// of method, following the client decode mode.
+func (c *Client) decode(method Method, base interface{}, target interface{}) error {
+	switch c.decodeMode {
+	case DecodeStrict:
+		return decodeStrict(base, target)
+	case DecodeLenient:
+		warnings := decodeLenient(base, target)
+		if len(warnings) > 0 && c.warningHandler != nil {
+			for i := range warnings {
+				warnings[i].Method = method
+			}
+			c.warningHandler(warnings)
+		}
+
+		return nil
+	}
+
+	return fillStruct(base, target)
+}
+
+func decodeStrict(base interface{}, target interface{}) error {
+	buf, err := json.Marshal(base)
+	if err != nil {
+		return err
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return err
+	}
+
+	// validated first, so shape mismatches are reported with their path
+	if err := validate(raw, reflect.TypeOf(target), ""); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(buf, target)
+}
+
+var (
+	numBoolType     = reflect.TypeOf(NumBool(false))
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// requiredArguments are the response arguments DecodeStrict expects for each
+// method, a response without them telling a daemon or proxy misbehaving.
+var requiredArguments = map[Method]string{
+	MethodTorrentGet:      "torrents",
+	MethodTorrentRename:   "id",
+	MethodSessionGet:      "version",
+	MethodSessionStats:    "torrentCount",
+	MethodFreeSpace:       "size-bytes",
+	MethodPortTest:        "port-is-open",
+	MethodBlockListUpdate: "blocklist-size",
+}
+
+// checkArguments reports, in DecodeStrict mode, a response missing the
+// required argument of method.
+func (c *Client) checkArguments(method Method, arguments map[string]interface{}) error {
+	key, ok := requiredArguments[method]
+	if !ok || c.decodeMode != DecodeStrict {
+		return nil
+	}
+
+	if _, ok := arguments[key]; !ok {
+		return &DecodeError{Field: key, Value: "null", Err: ErrMalformedValue}
+	}
+
+	return nil
+}
+
+// parseNumBool accepts the representations of NumBool sent by the daemon.
+func parseNumBool(raw interface{}) (bool, bool) {
+	switch v := raw.(type) {
+	case bool:
+		return v, true
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	}
+
+	return false, false
+}
+
+// validate walks the decoded json value along the target type, checking the
+// values encoding/json accepts but the library would silently misread.
+func validate(raw interface{}, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if raw == nil {
+		return nil
+	}
+
+	malformed := func() error {
+		value, _ := json.Marshal(raw)
+		return &DecodeError{Field: strings.TrimPrefix(path, "."), Value: string(value), Err: ErrMalformedValue}
+	}
+
+	if t == numBoolType {
+		if _, ok := parseNumBool(raw); !ok {
+			return malformed()
+		}
+
+		return nil
+	}
+
+	// other custom representations are checked by their UnmarshalJSON
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		list, ok := raw.([]interface{})
+		if !ok {
+			return malformed()
+		}
+
+		for i, item := range list {
+			if err := validate(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return malformed()
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := jsonName(field)
+			if name == "" {
+				continue
+			}
+
+			if err := validate(object[name], field.Type, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// jsonName returns the json key of a struct field, or "" when the field is
+// not decoded from json.
+func jsonName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+
+	return field.Name
+}
+
+// decodeLenient fills target, a pointer, with base. It never fails, the
+// values which can not be coerced are reported as warnings.
+func decodeLenient(base interface{}, target interface{}) []DecodeWarning {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return []DecodeWarning{{Message: "decode target must be a non-nil pointer"}}
+	}
+
+	// normalize the value, e.g. structs given as base
+	buf, err := json.Marshal(base)
+	if err != nil {
+		return []DecodeWarning{{Message: err.Error()}}
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return []DecodeWarning{{Message: err.Error()}}
+	}
+
+	d := lenientDecoder{}
+	d.value(raw, v.Elem(), "")
+
+	return d.warnings
+}
+
+type lenientDecoder struct {
+	warnings []DecodeWarning
+}
+
+func (d *lenientDecoder) warn(path string, raw interface{}, message string) {
+	value, _ := json.Marshal(raw)
+	d.warnings = append(d.warnings, DecodeWarning{
+		Field:   strings.TrimPrefix(path, "."),
+		Value:   string(value),
+		Message: message,
+	})
+}
+
+func (d *lenientDecoder) value(raw interface{}, v reflect.Value, path string) {
+	if raw == nil {
+		// null keeps the zero value, as encoding/json does
+		return
+	}
+
+	if v.Type() == numBoolType {
+		b, ok := d.bool(raw)
+		if !ok {
+			d.warn(path, raw, "expected a boolean")
+		} else if _, valid := parseNumBool(raw); !valid {
+			d.warn(path, raw, "coerced to a boolean")
+		}
+		v.SetBool(b)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		d.value(raw, v.Elem(), path)
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(raw))
+		}
+	case reflect.Bool:
+		b, ok := d.bool(raw)
+		if !ok {
+			d.warn(path, raw, "expected a boolean")
+			return
+		}
+		if _, valid := raw.(bool); !valid {
+			d.warn(path, raw, "coerced to a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := d.number(raw, path)
+		if !ok {
+			return
+		}
+		// checked as float64, converting out of range values is undefined
+		if math.IsNaN(n) || n < math.MinInt64 || n >= math.MaxInt64 || v.OverflowInt(int64(n)) {
+			d.warn(path, raw, "number out of range")
+			return
+		}
+		if n != math.Trunc(n) {
+			d.warn(path, raw, "number truncated to integer")
+		}
+		v.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := d.number(raw, path)
+		if !ok {
+			return
+		}
+		if math.IsNaN(n) || n < 0 || n >= math.MaxUint64 || v.OverflowUint(uint64(n)) {
+			d.warn(path, raw, "expected a positive number")
+			return
+		}
+		if n != math.Trunc(n) {
+			d.warn(path, raw, "number truncated to integer")
+		}
+		v.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, ok := d.number(raw, path)
+		if !ok {
+			return
+		}
+		v.SetFloat(n)
+	case reflect.String:
+		switch s := raw.(type) {
+		case string:
+			v.SetString(s)
+		case float64:
+			d.warn(path, raw, "coerced to a string")
+			v.SetString(strconv.FormatFloat(s, 'f', -1, 64))
+		case bool:
+			d.warn(path, raw, "coerced to a string")
+			v.SetString(strconv.FormatBool(s))
+		default:
+			d.warn(path, raw, "expected a string")
+		}
+	case reflect.Slice:
+		list, ok := raw.([]interface{})
+		if !ok {
+			d.warn(path, raw, "expected a list")
+			return
+		}
+		slice := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, item := range list {
+			d.value(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+		v.Set(slice)
+	case reflect.Struct:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			d.warn(path, raw, "expected an object")
+			return
+		}
+		d.object(object, v, path)
+	default:
+		// maps and other kinds are delegated to encoding/json
+		buf, _ := json.Marshal(raw)
+		if err := json.Unmarshal(buf, v.Addr().Interface()); err != nil {
+			d.warn(path, raw, err.Error())
+		}
+	}
+}
+
+func (d *lenientDecoder) object(object map[string]interface{}, v reflect.Value, path string) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" {
+			continue
+		}
+
+		raw, ok := object[name]
+		if !ok {
+			// encoding/json matches keys case-insensitively
+			for key, value := range object {
+				if strings.EqualFold(key, name) {
+					raw, ok = value, true
+					break
+				}
+			}
+		}
+
+		if ok {
+			d.value(raw, v.Field(i), path+"."+name)
+		}
+	}
+}
+
+func (d *lenientDecoder) bool(raw interface{}) (bool, bool) {
+	if b, ok := parseNumBool(raw); ok {
+		return b, true
+	}
+
+	if s, ok := raw.(string); ok {
+		if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+			return b, true
+		}
+	}
+
+	return false, false
+}
+
+// number returns raw as a number, warning about the values sent with another
+// type.
+func (d *lenientDecoder) number(raw interface{}, path string) (float64, bool) {
+	switch n := raw.(type) {
+	case float64:
+		return n, true
+	case bool:
+		d.warn(path, raw, "coerced to a number")
+		if n {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(n), 64); err == nil {
+			d.warn(path, raw, "coerced to a number")
+			return f, true
+		}
+	}
+
+	d.warn(path, raw, "expected a number")
+
+	return 0, false
+}
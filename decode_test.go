@@ -0,0 +1,222 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDecodeClient(t *testing.T, arguments string, opts ...Option) (*Client, func()) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"result": "success", "arguments": %s}`, arguments)
+	}))
+
+	client, err := New(append([]Option{WithURL(s.URL), WithHTTPClient(s.Client())}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, s.Close
+}
+
+func TestClient_DecodeStrict(t *testing.T) {
+	const malformed = `{"torrents": [{"id": 1, "trackerStats": [
+		{"id": 0, "lastScrapeTimedOut": 0},
+		{"id": 1, "lastScrapeTimedOut": "maybe"}
+	]}]}`
+
+	t.Run("should silently zero malformed values by default", func(st *testing.T) {
+		client, done := newDecodeClient(st, malformed)
+		defer done()
+
+		torrents, err := client.TorrentGet(context.Background(), TorrentGet{})
+		assert.NoError(st, err)
+		assert.Equal(st, NumBool(false), torrents[0].TrackerStats[1].LastScrapeTimedOut)
+	})
+
+	t.Run("should report malformed values in strict mode", func(st *testing.T) {
+		client, done := newDecodeClient(st, malformed, WithDecodeMode(DecodeStrict))
+		defer done()
+
+		_, err := client.TorrentGet(context.Background(), TorrentGet{})
+
+		var decodeErr *DecodeError
+		assert.True(st, errors.As(err, &decodeErr))
+		assert.True(st, errors.Is(err, ErrMalformedValue))
+		assert.Equal(st, "[0].trackerStats[1].lastScrapeTimedOut", decodeErr.Field)
+		assert.Equal(st, `"maybe"`, decodeErr.Value)
+	})
+
+	t.Run("should accept valid values in strict mode", func(st *testing.T) {
+		client, done := newDecodeClient(st, `{"torrents": [{"id": 1, "trackerStats": [
+			{"lastScrapeTimedOut": 1}, {"lastScrapeTimedOut": false}, {"lastScrapeTimedOut": null}, {}
+		]}]}`, WithDecodeMode(DecodeStrict))
+		defer done()
+
+		torrents, err := client.TorrentGet(context.Background(), TorrentGet{})
+		assert.NoError(st, err)
+		assert.Equal(st, NumBool(true), torrents[0].TrackerStats[0].LastScrapeTimedOut)
+	})
+
+	t.Run("should report type mismatches in both modes", func(st *testing.T) {
+		for _, mode := range []DecodeMode{DecodeDefault, DecodeStrict} {
+			client, done := newDecodeClient(st, `{"version": 3}`, WithDecodeMode(mode))
+
+			_, err := client.SessionGet(context.Background())
+			assert.Error(st, err)
+
+			done()
+		}
+	})
+
+	t.Run("should report objects given for lists in strict mode", func(st *testing.T) {
+		client, done := newDecodeClient(st, `{"torrents": [{"id": 1, "trackerStats": {"id": 0}}]}`, WithDecodeMode(DecodeStrict))
+		defer done()
+
+		_, err := client.TorrentGet(context.Background(), TorrentGet{})
+
+		var decodeErr *DecodeError
+		assert.True(st, errors.As(err, &decodeErr))
+		assert.Equal(st, "[0].trackerStats", decodeErr.Field)
+		assert.Equal(st, `{"id":0}`, decodeErr.Value)
+	})
+
+	t.Run("should require the method arguments in strict mode", func(st *testing.T) {
+		for _, mode := range []DecodeMode{DecodeDefault, DecodeStrict} {
+			client, done := newDecodeClient(st, `{}`, WithDecodeMode(mode))
+
+			torrents, err := client.TorrentGet(context.Background(), TorrentGet{})
+			if mode == DecodeStrict {
+				var decodeErr *DecodeError
+				assert.True(st, errors.As(err, &decodeErr))
+				assert.Equal(st, "torrents", decodeErr.Field)
+			} else {
+				assert.NoError(st, err)
+				assert.Empty(st, torrents)
+			}
+
+			_, err = client.SessionStats(context.Background())
+			assert.Equal(st, mode == DecodeStrict, errors.Is(err, ErrMalformedValue))
+
+			done()
+		}
+	})
+
+	t.Run("should require torrent-added or torrent-duplicate in strict mode", func(st *testing.T) {
+		client, done := newDecodeClient(st, `{}`, WithDecodeMode(DecodeStrict))
+		defer done()
+
+		_, err := client.TorrentAdd(context.Background(), TorrentAdd{})
+		assert.True(st, errors.Is(err, ErrMalformedValue))
+	})
+}
+
+func TestClient_DecodeLenient(t *testing.T) {
+	const drifted = `{"torrents": [{
+		"id": "7",
+		"name": 42,
+		"isFinished": 1,
+		"totalSize": "1024",
+		"eta": -1,
+		"labels": null,
+		"trackerStats": [{"id": 0, "lastScrapeTimedOut": "true"}, {"id": 1, "lastScrapeTimedOut": "maybe"}],
+		"peers": [{"address": "10.0.0.2", "isEncrypted": 1, "rateToClient": "512"}],
+		"files": "none"
+	}]}`
+
+	t.Run("should fail on drifted types by default", func(st *testing.T) {
+		client, done := newDecodeClient(st, drifted)
+		defer done()
+
+		_, err := client.TorrentGet(context.Background(), TorrentGet{})
+		assert.Error(st, err)
+	})
+
+	t.Run("should coerce drifted types and report the rest", func(st *testing.T) {
+		var warnings []DecodeWarning
+
+		client, done := newDecodeClient(st, drifted, WithDecodeMode(DecodeLenient), WithDecodeWarnings(func(w []DecodeWarning) {
+			warnings = append(warnings, w...)
+		}))
+		defer done()
+
+		torrents, err := client.TorrentGet(context.Background(), TorrentGet{})
+		assert.NoError(st, err)
+		assert.Len(st, torrents, 1)
+
+		torrent := torrents[0]
+		assert.Equal(st, int64(7), torrent.ID)
+		assert.Equal(st, "42", torrent.Name)
+		assert.True(st, torrent.IsFinished)
+		assert.Equal(st, int64(1024), torrent.TotalSize)
+		assert.Equal(st, int64(-1), torrent.Eta)
+		assert.Nil(st, torrent.Labels)
+		assert.Equal(st, NumBool(true), torrent.TrackerStats[0].LastScrapeTimedOut)
+		assert.Equal(st, NumBool(false), torrent.TrackerStats[1].LastScrapeTimedOut)
+		assert.True(st, torrent.Peers[0].IsEncrypted)
+		assert.Equal(st, int64(512), torrent.Peers[0].RateToClient)
+		assert.Empty(st, torrent.Files)
+
+		messages := make(map[string]string)
+		for _, w := range warnings {
+			assert.Equal(st, MethodTorrentGet, w.Method)
+			messages[w.Field+" "+w.Value] = w.Message
+		}
+		assert.Equal(st, map[string]string{
+			`[0].id "7"`:           "coerced to a number",
+			`[0].name 42`:          "coerced to a string",
+			`[0].isFinished 1`:     "coerced to a boolean",
+			`[0].totalSize "1024"`: "coerced to a number",
+			`[0].trackerStats[0].lastScrapeTimedOut "true"`:  "coerced to a boolean",
+			`[0].trackerStats[1].lastScrapeTimedOut "maybe"`: "expected a boolean",
+			`[0].peers[0].isEncrypted 1`:                     "coerced to a boolean",
+			`[0].peers[0].rateToClient "512"`:                "coerced to a number",
+			`[0].files "none"`:                               "expected a list",
+		}, messages)
+	})
+
+	t.Run("should drop numbers out of range", func(st *testing.T) {
+		var warnings []DecodeWarning
+
+		client, done := newDecodeClient(st, `{"torrents": [{"id": 1e300, "totalSize": "-1e19", "eta": "NaN", "peer-limit": 2.5}]}`,
+			WithDecodeMode(DecodeLenient), WithDecodeWarnings(func(w []DecodeWarning) {
+				warnings = append(warnings, w...)
+			}))
+		defer done()
+
+		torrents, err := client.TorrentGet(context.Background(), TorrentGet{})
+		assert.NoError(st, err)
+		assert.Equal(st, int64(0), torrents[0].ID)
+		assert.Equal(st, int64(0), torrents[0].TotalSize)
+		assert.Equal(st, int64(0), torrents[0].Eta)
+		assert.Equal(st, int64(2), torrents[0].PeerLimit)
+
+		messages := make(map[string][]string)
+		for _, w := range warnings {
+			messages[w.Field] = append(messages[w.Field], w.Message)
+		}
+		assert.Equal(st, map[string][]string{
+			"[0].id":         {"number out of range"},
+			"[0].totalSize":  {"coerced to a number", "number out of range"},
+			"[0].eta":        {"coerced to a number", "number out of range"},
+			"[0].peer-limit": {"number truncated to integer"},
+		}, messages)
+	})
+
+	t.Run("should coerce session values", func(st *testing.T) {
+		client, done := newDecodeClient(st, `{"dht-enabled": 0, "peer-limit-global": "200", "version": 3.0}`,
+			WithDecodeMode(DecodeLenient))
+		defer done()
+
+		session, err := client.SessionGet(context.Background())
+		assert.NoError(st, err)
+		assert.False(st, session.DhtEnabled)
+		assert.Equal(st, int64(200), session.PeerLimitGlobal)
+		assert.Equal(st, "3", session.Version)
+	})
+}
@@ -0,0 +1,184 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrNoHealthyEndpoint = errors.New("no healthy endpoint available")
+)
+
+// endpoint keeps the session id negotiated with each url, so switching back
+// to a known endpoint does not need a new handshake.
+type endpoint struct {
+	url       string
+	sessionID string
+}
+
+// WithEndpoints configures an ordered list of urls reaching the same daemon
+// (e.g. through different proxies). Requests go to the active endpoint and,
+// on transport errors, fail over to the next one. It replaces WithURL, and
+// every url is completed like the WithURL one.
+func WithEndpoints(urls ...string) Option {
+	return func(c *Client) {
+		c.endpoints = make([]*endpoint, 0, len(urls))
+		for _, u := range urls {
+			c.endpoints = append(c.endpoints, &endpoint{url: u})
+		}
+	}
+}
+
+func (c *Client) resolveEndpoints() error {
+	if len(c.endpoints) == 0 {
+		return nil
+	}
+
+	for _, e := range c.endpoints {
+		u, err := c.buildURL(e.url)
+		if err != nil {
+			return err
+		}
+		e.url = u
+	}
+
+	c.active = 0
+	c.URL = c.endpoints[0].url
+
+	return nil
+}
+
+// ActiveEndpoint returns the url currently receiving the requests.
+func (c *Client) ActiveEndpoint() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.URL
+}
+
+// Endpoints returns the configured urls, in order of preference.
+func (c *Client) Endpoints() []string {
+	if len(c.endpoints) == 0 {
+		return []string{c.ActiveEndpoint()}
+	}
+
+	urls := make([]string, 0, len(c.endpoints))
+	for _, e := range c.endpoints {
+		urls = append(urls, e.url)
+	}
+
+	return urls
+}
+
+// activate makes the endpoint at index the active one, keeping the session
+// id of the previous endpoint for later use.
+func (c *Client) activate(index int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.activateLocked(index)
+}
+
+func (c *Client) activateLocked(index int) {
+	c.endpoints[c.active].sessionID = c.SessionID
+	c.active = index
+	c.URL = c.endpoints[index].url
+	c.SessionID = c.endpoints[index].sessionID
+}
+
+// failover moves to the endpoint after url, unless another request already
+// moved away from it, so concurrent failures skip a single endpoint.
+func (c *Client) failover(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.URL == url {
+		c.activateLocked((c.active + 1) % len(c.endpoints))
+	}
+}
+
+// sessionID returns the session id negotiated with url. c.mu must be held.
+func (c *Client) sessionID(url string) string {
+	if url == c.URL {
+		return c.SessionID
+	}
+
+	for _, e := range c.endpoints {
+		if e.url == url {
+			return e.sessionID
+		}
+	}
+
+	return ""
+}
+
+// setSessionID keeps the session id negotiated with url. c.mu must be held.
+func (c *Client) setSessionID(url, sessionID string) {
+	if url == c.URL {
+		c.SessionID = sessionID
+		return
+	}
+
+	for _, e := range c.endpoints {
+		if e.url == url {
+			e.sessionID = sessionID
+		}
+	}
+}
+
+// CheckEndpoints pings the endpoints in order of preference and activates the
+// first healthy one, which allows going back to the preferred endpoint once
+// it recovers. The requests in flight keep using the active endpoint while
+// the others are probed.
+func (c *Client) CheckEndpoints(ctx context.Context) error {
+	if len(c.endpoints) == 0 {
+		return c.Ping(ctx)
+	}
+
+	var errs []error
+
+	for i, e := range c.endpoints {
+		if err := c.ping(ctx, e.url); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.url, err))
+			continue
+		}
+
+		c.mu.Lock()
+		if c.active != i {
+			c.activateLocked(i)
+		}
+		c.mu.Unlock()
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v", ErrNoHealthyEndpoint, errs)
+}
+
+// send performs the request on the active endpoint, moving to the next
+// endpoints when the daemon can not be reached. A non empty endpoint is the
+// only url tried.
+func (c *Client) send(ctx context.Context, body []byte, maxRetries int, endpoint string) (*http.Response, error) {
+	if endpoint != "" {
+		return c.doRequest(ctx, endpoint, body, maxRetries)
+	}
+
+	attempts := len(c.endpoints)
+	if attempts == 0 {
+		attempts = 1
+	}
+
+	for {
+		url := c.ActiveEndpoint()
+		resp, err := c.doRequest(ctx, url, body, maxRetries)
+		attempts--
+
+		if err == nil || !errors.Is(err, errTransport) || ctx.Err() != nil || attempts <= 0 {
+			return resp, err
+		}
+
+		c.failover(url)
+	}
+}
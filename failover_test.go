@@ -0,0 +1,189 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSessionServer emulates the daemon session-id handshake and counts the
+// requests it receives.
+func newSessionServer(sessionID string, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if r.Header.Get(SessionIDHeader) != sessionID {
+			w.Header().Set(SessionIDHeader, sessionID)
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		_, _ = w.Write([]byte(`{"result": "success"}`))
+	}))
+}
+
+func TestClient_Failover(t *testing.T) {
+	t.Run("should resolve every endpoint", func(st *testing.T) {
+		client, err := New(WithEndpoints("lan.local", "https://vpn.example.com"))
+		assert.NoError(st, err)
+		assert.Equal(st, []string{
+			"http://lan.local:9091/transmission/rpc",
+			"https://vpn.example.com/transmission/rpc",
+		}, client.Endpoints())
+		assert.Equal(st, "http://lan.local:9091/transmission/rpc", client.ActiveEndpoint())
+	})
+
+	t.Run("should fail with an invalid endpoint", func(st *testing.T) {
+		client, err := New(WithEndpoints("lan.local", "ftp://vpn.example.com"))
+		assert.Nil(st, client)
+		assert.True(st, errors.Is(err, ErrInvalidURL))
+	})
+
+	t.Run("should return the url as single endpoint", func(st *testing.T) {
+		client, _ := New(WithURL("nas"))
+		assert.Equal(st, []string{"http://nas:9091/transmission/rpc"}, client.Endpoints())
+	})
+
+	t.Run("should fail over when the active endpoint goes down", func(st *testing.T) {
+		var lanHits, vpnHits int32
+		lan := newSessionServer("lan-session", &lanHits)
+		vpn := newSessionServer("vpn-session", &vpnHits)
+		defer vpn.Close()
+
+		client, _ := New(WithEndpoints(lan.URL, vpn.URL))
+		assert.NoError(st, client.SessionClose(context.Background()))
+		assert.Equal(st, lan.URL+DefaultRPCPath, client.ActiveEndpoint())
+		assert.Equal(st, "lan-session", client.SessionID)
+
+		lan.Close()
+
+		assert.NoError(st, client.SessionClose(context.Background()))
+		assert.Equal(st, vpn.URL+DefaultRPCPath, client.ActiveEndpoint())
+		assert.Equal(st, "vpn-session", client.SessionID)
+		assert.Equal(st, int32(2), atomic.LoadInt32(&lanHits))
+		assert.Equal(st, int32(2), atomic.LoadInt32(&vpnHits))
+	})
+
+	t.Run("should return the error when every endpoint is down", func(st *testing.T) {
+		var hits int32
+		lan := newSessionServer("lan-session", &hits)
+		vpn := newSessionServer("vpn-session", &hits)
+		lan.Close()
+		vpn.Close()
+
+		client, _ := New(WithEndpoints(lan.URL, vpn.URL))
+		err := client.SessionClose(context.Background())
+		assert.True(st, errors.Is(err, errTransport))
+		assert.Equal(st, int32(0), hits)
+	})
+
+	t.Run("should not fail over on rpc errors", func(st *testing.T) {
+		var hits int32
+		lan := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"result": "no such method"}`))
+		}))
+		defer lan.Close()
+		vpn := newSessionServer("vpn-session", &hits)
+		defer vpn.Close()
+
+		client, _ := New(WithEndpoints(lan.URL, vpn.URL))
+		assert.Error(st, client.SessionClose(context.Background()))
+		assert.Equal(st, lan.URL+DefaultRPCPath, client.ActiveEndpoint())
+		assert.Equal(st, int32(0), hits)
+	})
+
+	t.Run("should go back to the preferred endpoint reusing its session id", func(st *testing.T) {
+		var lanHits, vpnHits int32
+		lan := newSessionServer("lan-session", &lanHits)
+		defer lan.Close()
+		vpn := newSessionServer("vpn-session", &vpnHits)
+		defer vpn.Close()
+
+		client, _ := New(WithEndpoints(lan.URL, vpn.URL))
+		assert.NoError(st, client.SessionClose(context.Background()))
+
+		client.activate(1)
+		assert.NoError(st, client.SessionClose(context.Background()))
+		assert.Equal(st, "vpn-session", client.SessionID)
+
+		assert.NoError(st, client.CheckEndpoints(context.Background()))
+		assert.Equal(st, lan.URL+DefaultRPCPath, client.ActiveEndpoint())
+		assert.Equal(st, "lan-session", client.SessionID)
+
+		// the session id is still valid, no handshake is needed
+		hits := atomic.LoadInt32(&lanHits)
+		assert.NoError(st, client.SessionClose(context.Background()))
+		assert.Equal(st, hits+1, atomic.LoadInt32(&lanHits))
+	})
+
+	t.Run("should fail over once for concurrent requests", func(st *testing.T) {
+		var lanHits, vpnHits, wanHits int32
+		lan := newSessionServer("lan-session", &lanHits)
+		lan.Close()
+		vpn := newSessionServer("vpn-session", &vpnHits)
+		defer vpn.Close()
+		wan := newSessionServer("wan-session", &wanHits)
+		defer wan.Close()
+
+		client, _ := New(WithEndpoints(lan.URL, vpn.URL, wan.URL))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(st, client.SessionClose(context.Background()))
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(st, vpn.URL+DefaultRPCPath, client.ActiveEndpoint())
+		assert.Equal(st, int32(0), atomic.LoadInt32(&wanHits))
+	})
+
+	t.Run("should keep the active endpoint while probing the others", func(st *testing.T) {
+		var vpnHits int32
+		var probed string
+		var client *Client
+		lan := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			probed = client.ActiveEndpoint()
+			_, _ = w.Write([]byte(`{"result": "success"}`))
+		}))
+		defer lan.Close()
+		vpn := newSessionServer("vpn-session", &vpnHits)
+		defer vpn.Close()
+
+		client, _ = New(WithEndpoints(lan.URL, vpn.URL))
+		client.activate(1)
+
+		assert.NoError(st, client.CheckEndpoints(context.Background()))
+		assert.Equal(st, vpn.URL+DefaultRPCPath, probed)
+		assert.Equal(st, lan.URL+DefaultRPCPath, client.ActiveEndpoint())
+	})
+
+	t.Run("should activate the first healthy endpoint", func(st *testing.T) {
+		var hits int32
+		lan := newSessionServer("lan-session", &hits)
+		lan.Close()
+		vpn := newSessionServer("vpn-session", &hits)
+		defer vpn.Close()
+
+		client, _ := New(WithEndpoints(lan.URL, vpn.URL))
+		assert.NoError(st, client.CheckEndpoints(context.Background()))
+		assert.Equal(st, vpn.URL+DefaultRPCPath, client.ActiveEndpoint())
+	})
+
+	t.Run("should fail health check when every endpoint is down", func(st *testing.T) {
+		var hits int32
+		lan := newSessionServer("lan-session", &hits)
+		lan.Close()
+
+		client, _ := New(WithEndpoints(lan.URL))
+		err := client.CheckEndpoints(context.Background())
+		assert.True(st, errors.Is(err, ErrNoHealthyEndpoint))
+	})
+}
@@ -0,0 +1,334 @@
+package transmission
+
+import (
+	"context"
+	"sync"
+)
+
+// Call is a method invocation recorded by Fake.
+type Call struct {
+	Method    Method
+	Arguments interface{}
+}
+
+// Fake is an in-memory API implementation for unit tests. Each method calls
+// its programmable function field when set; otherwise it returns the zero
+// value and the error registered in Errors for the method, if any. Every
+// call is recorded, and Fake is safe for concurrent use once configured.
+//
+//	fake := &transmission.Fake{
+//		TorrentGetFunc: func(ctx context.Context, args transmission.TorrentGet) ([]transmission.Torrent, error) {
+//			return []transmission.Torrent{{ID: 1, Name: "debian.iso"}}, nil
+//		},
+//	}
+type Fake struct {
+	TorrentStartFunc      func(ctx context.Context, args Filter) error
+	TorrentStartNowFunc   func(ctx context.Context, args Filter) error
+	TorrentStopFunc       func(ctx context.Context, args Filter) error
+	TorrentVerifyFunc     func(ctx context.Context, args Filter) error
+	TorrentReannounceFunc func(ctx context.Context, args Filter) error
+	TorrentGetFunc        func(ctx context.Context, args TorrentGet) ([]Torrent, error)
+	TorrentRenameFunc     func(ctx context.Context, args TorrentRename) (Torrent, error)
+	TorrentSetFunc        func(ctx context.Context, args TorrentSet) error
+	TorrentAddFunc        func(ctx context.Context, args TorrentAdd) (AddResult, error)
+	TorrentRemoveFunc     func(ctx context.Context, args TorrentRemove) error
+	TorrentMoveFunc       func(ctx context.Context, args TorrentMove) error
+	PingFunc              func(ctx context.Context) error
+	SessionSetFunc        func(ctx context.Context, args SessionSet) error
+	SessionGetFunc        func(ctx context.Context) (Session, error)
+	SessionStatsFunc      func(ctx context.Context) (SessionStats, error)
+	SessionCloseFunc      func(ctx context.Context) error
+	FreeSpaceFunc         func(ctx context.Context, args FreeSpace) (FreeSpace, error)
+	PortCheckFunc         func(ctx context.Context) (PortCheck, error)
+	BlockListUpdateFunc   func(ctx context.Context) (BlockList, error)
+	QueueMoveTopFunc      func(ctx context.Context, args Filter) error
+	QueueMoveBottomFunc   func(ctx context.Context, args Filter) error
+	QueueMoveUpFunc       func(ctx context.Context, args Filter) error
+	QueueMoveDownFunc     func(ctx context.Context, args Filter) error
+
+	Errors map[Method]error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (f *Fake) record(method Method, args interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, Call{Method: method, Arguments: args})
+}
+
+func (f *Fake) err(method Method) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.Errors[method]
+}
+
+// Calls returns the recorded calls, in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the recorded calls of the given method, in order.
+func (f *Fake) CallsTo(method Method) []Call {
+	var calls []Call
+
+	for _, call := range f.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// Reset forgets the recorded calls.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = nil
+}
+
+func (f *Fake) TorrentStart(ctx context.Context, args Filter) error {
+	f.record(MethodTorrentStart, args)
+
+	if f.TorrentStartFunc == nil {
+		return f.err(MethodTorrentStart)
+	}
+
+	return f.TorrentStartFunc(ctx, args)
+}
+
+func (f *Fake) TorrentStartNow(ctx context.Context, args Filter) error {
+	f.record(MethodTorrentStartNow, args)
+
+	if f.TorrentStartNowFunc == nil {
+		return f.err(MethodTorrentStartNow)
+	}
+
+	return f.TorrentStartNowFunc(ctx, args)
+}
+
+func (f *Fake) TorrentStop(ctx context.Context, args Filter) error {
+	f.record(MethodTorrentStop, args)
+
+	if f.TorrentStopFunc == nil {
+		return f.err(MethodTorrentStop)
+	}
+
+	return f.TorrentStopFunc(ctx, args)
+}
+
+func (f *Fake) TorrentVerify(ctx context.Context, args Filter) error {
+	f.record(MethodTorrentVerify, args)
+
+	if f.TorrentVerifyFunc == nil {
+		return f.err(MethodTorrentVerify)
+	}
+
+	return f.TorrentVerifyFunc(ctx, args)
+}
+
+func (f *Fake) TorrentReannounce(ctx context.Context, args Filter) error {
+	f.record(MethodTorrentReannounce, args)
+
+	if f.TorrentReannounceFunc == nil {
+		return f.err(MethodTorrentReannounce)
+	}
+
+	return f.TorrentReannounceFunc(ctx, args)
+}
+
+func (f *Fake) TorrentGet(ctx context.Context, args TorrentGet) ([]Torrent, error) {
+	f.record(MethodTorrentGet, args)
+
+	if f.TorrentGetFunc == nil {
+		var zero []Torrent
+		return zero, f.err(MethodTorrentGet)
+	}
+
+	return f.TorrentGetFunc(ctx, args)
+}
+
+func (f *Fake) TorrentRename(ctx context.Context, args TorrentRename) (Torrent, error) {
+	f.record(MethodTorrentRename, args)
+
+	if f.TorrentRenameFunc == nil {
+		var zero Torrent
+		return zero, f.err(MethodTorrentRename)
+	}
+
+	return f.TorrentRenameFunc(ctx, args)
+}
+
+func (f *Fake) TorrentSet(ctx context.Context, args TorrentSet) error {
+	f.record(MethodTorrentSet, args)
+
+	if f.TorrentSetFunc == nil {
+		return f.err(MethodTorrentSet)
+	}
+
+	return f.TorrentSetFunc(ctx, args)
+}
+
+func (f *Fake) TorrentAdd(ctx context.Context, args TorrentAdd) (AddResult, error) {
+	f.record(MethodTorrentAdd, args)
+
+	if f.TorrentAddFunc == nil {
+		var zero AddResult
+		return zero, f.err(MethodTorrentAdd)
+	}
+
+	return f.TorrentAddFunc(ctx, args)
+}
+
+func (f *Fake) TorrentRemove(ctx context.Context, args TorrentRemove) error {
+	f.record(MethodTorrentRemove, args)
+
+	if f.TorrentRemoveFunc == nil {
+		return f.err(MethodTorrentRemove)
+	}
+
+	return f.TorrentRemoveFunc(ctx, args)
+}
+
+func (f *Fake) TorrentMove(ctx context.Context, args TorrentMove) error {
+	f.record(MethodTorrentMove, args)
+
+	if f.TorrentMoveFunc == nil {
+		return f.err(MethodTorrentMove)
+	}
+
+	return f.TorrentMoveFunc(ctx, args)
+}
+
+func (f *Fake) Ping(ctx context.Context) error {
+	f.record(MethodPing, nil)
+
+	if f.PingFunc == nil {
+		return f.err(MethodPing)
+	}
+
+	return f.PingFunc(ctx)
+}
+
+func (f *Fake) SessionSet(ctx context.Context, args SessionSet) error {
+	f.record(MethodSessionSet, args)
+
+	if f.SessionSetFunc == nil {
+		return f.err(MethodSessionSet)
+	}
+
+	return f.SessionSetFunc(ctx, args)
+}
+
+func (f *Fake) SessionGet(ctx context.Context) (Session, error) {
+	f.record(MethodSessionGet, nil)
+
+	if f.SessionGetFunc == nil {
+		var zero Session
+		return zero, f.err(MethodSessionGet)
+	}
+
+	return f.SessionGetFunc(ctx)
+}
+
+func (f *Fake) SessionStats(ctx context.Context) (SessionStats, error) {
+	f.record(MethodSessionStats, nil)
+
+	if f.SessionStatsFunc == nil {
+		var zero SessionStats
+		return zero, f.err(MethodSessionStats)
+	}
+
+	return f.SessionStatsFunc(ctx)
+}
+
+func (f *Fake) SessionClose(ctx context.Context) error {
+	f.record(MethodSessionClose, nil)
+
+	if f.SessionCloseFunc == nil {
+		return f.err(MethodSessionClose)
+	}
+
+	return f.SessionCloseFunc(ctx)
+}
+
+func (f *Fake) FreeSpace(ctx context.Context, args FreeSpace) (FreeSpace, error) {
+	f.record(MethodFreeSpace, args)
+
+	if f.FreeSpaceFunc == nil {
+		var zero FreeSpace
+		return zero, f.err(MethodFreeSpace)
+	}
+
+	return f.FreeSpaceFunc(ctx, args)
+}
+
+func (f *Fake) PortCheck(ctx context.Context) (PortCheck, error) {
+	f.record(MethodPortTest, nil)
+
+	if f.PortCheckFunc == nil {
+		var zero PortCheck
+		return zero, f.err(MethodPortTest)
+	}
+
+	return f.PortCheckFunc(ctx)
+}
+
+func (f *Fake) BlockListUpdate(ctx context.Context) (BlockList, error) {
+	f.record(MethodBlockListUpdate, nil)
+
+	if f.BlockListUpdateFunc == nil {
+		var zero BlockList
+		return zero, f.err(MethodBlockListUpdate)
+	}
+
+	return f.BlockListUpdateFunc(ctx)
+}
+
+func (f *Fake) QueueMoveTop(ctx context.Context, args Filter) error {
+	f.record(MethodQueueMoveTop, args)
+
+	if f.QueueMoveTopFunc == nil {
+		return f.err(MethodQueueMoveTop)
+	}
+
+	return f.QueueMoveTopFunc(ctx, args)
+}
+
+func (f *Fake) QueueMoveBottom(ctx context.Context, args Filter) error {
+	f.record(MethodQueueMoveBottom, args)
+
+	if f.QueueMoveBottomFunc == nil {
+		return f.err(MethodQueueMoveBottom)
+	}
+
+	return f.QueueMoveBottomFunc(ctx, args)
+}
+
+func (f *Fake) QueueMoveUp(ctx context.Context, args Filter) error {
+	f.record(MethodQueueMoveUp, args)
+
+	if f.QueueMoveUpFunc == nil {
+		return f.err(MethodQueueMoveUp)
+	}
+
+	return f.QueueMoveUpFunc(ctx, args)
+}
+
+func (f *Fake) QueueMoveDown(ctx context.Context, args Filter) error {
+	f.record(MethodQueueMoveDown, args)
+
+	if f.QueueMoveDownFunc == nil {
+		return f.err(MethodQueueMoveDown)
+	}
+
+	return f.QueueMoveDownFunc(ctx, args)
+}
@@ -0,0 +1,92 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stoppedNames is an example of code depending on a small interface.
+func stoppedNames(ctx context.Context, api TorrentAPI) ([]string, error) {
+	torrents, err := api.TorrentGet(ctx, TorrentGet{Fields: []string{"name", "status"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, torrent := range torrents {
+		if torrent.Status == 0 {
+			names = append(names, torrent.Name)
+		}
+	}
+
+	return names, nil
+}
+
+func TestFake(t *testing.T) {
+	t.Run("should return programmed responses", func(st *testing.T) {
+		fake := &Fake{
+			TorrentGetFunc: func(ctx context.Context, args TorrentGet) ([]Torrent, error) {
+				return []Torrent{{Name: "stopped", Status: 0}, {Name: "seeding", Status: 6}}, nil
+			},
+		}
+
+		names, err := stoppedNames(context.Background(), fake)
+		assert.NoError(st, err)
+		assert.Equal(st, []string{"stopped"}, names)
+	})
+
+	t.Run("should return zero values and registered errors", func(st *testing.T) {
+		failure := errors.New("daemon down")
+		fake := &Fake{Errors: map[Method]error{MethodSessionGet: failure}}
+
+		session, err := fake.SessionGet(context.Background())
+		assert.Equal(st, Session{}, session)
+		assert.Equal(st, failure, err)
+
+		torrents, err := fake.TorrentGet(context.Background(), TorrentGet{})
+		assert.Nil(st, torrents)
+		assert.NoError(st, err)
+	})
+
+	t.Run("should record calls", func(st *testing.T) {
+		fake := &Fake{}
+		ctx := context.Background()
+
+		_ = fake.Ping(ctx)
+		_ = fake.TorrentStop(ctx, Filter{Ids: []int64{1}})
+		_ = fake.TorrentStart(ctx, Filter{Ids: []int64{2}})
+		_ = fake.TorrentStop(ctx, Filter{Ids: []int64{3}})
+		_ = fake.QueueMoveTop(ctx, Filter{Ids: []int64{3}})
+
+		assert.Equal(st, []Call{
+			{Method: MethodPing},
+			{Method: MethodTorrentStop, Arguments: Filter{Ids: []int64{1}}},
+			{Method: MethodTorrentStart, Arguments: Filter{Ids: []int64{2}}},
+			{Method: MethodTorrentStop, Arguments: Filter{Ids: []int64{3}}},
+			{Method: MethodQueueMoveTop, Arguments: Filter{Ids: []int64{3}}},
+		}, fake.Calls())
+		assert.Len(st, fake.CallsTo(MethodTorrentStop), 2)
+
+		fake.Reset()
+		assert.Empty(st, fake.Calls())
+	})
+
+	t.Run("should be usable through every interface", func(st *testing.T) {
+		var (
+			torrents TorrentAPI = &Fake{}
+			session  SessionAPI = &Fake{}
+			queue    QueueAPI   = &Fake{}
+			client   API
+		)
+
+		client, _ = New()
+
+		assert.NotNil(st, torrents)
+		assert.NotNil(st, session)
+		assert.NotNil(st, queue)
+		assert.NotNil(st, client)
+	})
+}
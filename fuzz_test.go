@@ -0,0 +1,101 @@
+//go:build go1.18
+// +build go1.18
+
+package transmission
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// fuzzDecode checks that decoding arbitrary arguments never panics, and that
+// anything accepted by DecodeStrict decodes to the same value by default.
+func fuzzDecode(t *testing.T, data []byte, newTarget func() interface{}) {
+	var arguments interface{}
+	if json.Unmarshal(data, &arguments) != nil {
+		return
+	}
+
+	strict := &Client{decodeMode: DecodeStrict}
+	base := &Client{}
+	lenient := &Client{decodeMode: DecodeLenient}
+
+	strictTarget, defaultTarget := newTarget(), newTarget()
+	strictErr := strict.decode(MethodTorrentGet, arguments, strictTarget)
+	defaultErr := base.decode(MethodTorrentGet, arguments, defaultTarget)
+
+	if err := lenient.decode(MethodTorrentGet, arguments, newTarget()); err != nil {
+		t.Fatalf("lenient mode failed on %s: %v", data, err)
+	}
+
+	if strictErr == nil && defaultErr != nil {
+		t.Fatalf("strict mode accepted %s, default mode failed: %v", data, defaultErr)
+	}
+
+	if strictErr == nil && !reflect.DeepEqual(strictTarget, defaultTarget) {
+		t.Fatalf("strict and default modes decoded %s differently", data)
+	}
+}
+
+func FuzzResponse(f *testing.F) {
+	f.Add([]byte(`{"result": "success", "arguments": {"torrents": []}, "tag": 1}`))
+	f.Add([]byte(`{"result": "no such method"}`))
+	f.Add([]byte(`{"arguments": []}`))
+	f.Add([]byte(dataStr))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var res response
+		_ = json.Unmarshal(data, &res)
+	})
+}
+
+func FuzzTorrent(f *testing.F) {
+	f.Add([]byte(`[{"id": 1, "name": "debian", "trackerStats": [{"lastScrapeTimedOut": 1}]}]`))
+	f.Add([]byte(`[{"trackerStats": [{"lastScrapeTimedOut": "maybe"}]}]`))
+	f.Add([]byte(`[{"files": null, "peers": [{"port": "51413"}], "eta": -1}]`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzDecode(t, data, func() interface{} { return &[]Torrent{} })
+	})
+}
+
+func FuzzSession(f *testing.F) {
+	f.Add([]byte(`{"version": "3.00", "alt-speed-enabled": true, "units": {"speed-units": ["kB/s"]}}`))
+	f.Add([]byte(`{"peer-port": "51413"}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzDecode(t, data, func() interface{} { return &Session{} })
+	})
+}
+
+func FuzzSessionStats(f *testing.F) {
+	f.Add([]byte(`{"torrentCount": 1, "cumulative-stats": {"uploadedBytes": 1}, "current-stats": {}}`))
+	f.Add([]byte(`{"downloadSpeed": 1.5}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzDecode(t, data, func() interface{} { return &SessionStats{} })
+	})
+}
+
+func FuzzTorrentAdd(f *testing.F) {
+	f.Add([]byte(`{"torrent-added": {"id": 1, "name": "debian", "hashString": "aaaa"}}`))
+	f.Add([]byte(`{"torrent-duplicate": {"id": 1}}`))
+	f.Add([]byte(`{"torrent-added": {"id": 2}, "torrent-duplicate": {"id": 1}}`))
+	f.Add([]byte(`{"torrent-added": 3}`))
+	f.Add([]byte(`{}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var arguments map[string]interface{}
+		if json.Unmarshal(data, &arguments) != nil {
+			return
+		}
+
+		strictTorrent, strictErr := (&Client{decodeMode: DecodeStrict}).decodeTorrentAdd(arguments)
+		defaultTorrent, defaultErr := (&Client{}).decodeTorrentAdd(arguments)
+
+		if strictErr == nil && (defaultErr != nil || !reflect.DeepEqual(strictTorrent, defaultTorrent)) {
+			t.Fatalf("strict and default modes disagree on %s", data)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package transmission
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultHandleFields are the fields requested by TorrentHandle.Refresh when
+// none are given.
+var DefaultHandleFields = []string{
+	"id", "hashString", "name", "status", "error", "errorString", "percentDone",
+	"downloadDir", "labels", "queuePosition", "totalSize",
+}
+
+// TorrentHandle is a torrent bound to the client which returned it. Calls
+// address the torrent by its hash, which unlike the id survives daemon
+// restarts, and fall back to the id when the hash was not fetched.
+//
+// A handle is not safe for concurrent use: Refresh replaces the embedded
+// Torrent.
+type TorrentHandle struct {
+	Torrent
+	client *Client
+}
+
+// Handle binds torrent to the client.
+func (c *Client) Handle(torrent Torrent) *TorrentHandle {
+	return &TorrentHandle{Torrent: torrent, client: c}
+}
+
+// Handles runs TorrentGet and binds the result to the client. The hash is
+// always requested, when args lists fields.
+func (c *Client) Handles(ctx context.Context, args TorrentGet) ([]*TorrentHandle, error) {
+	args.Fields = withHash(args.Fields)
+
+	torrents, err := c.TorrentGet(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	handles := make([]*TorrentHandle, len(torrents))
+	for i, torrent := range torrents {
+		handles[i] = c.Handle(torrent)
+	}
+
+	return handles, nil
+}
+
+func withHash(fields []string) []string {
+	if len(fields) == 0 {
+		return fields
+	}
+
+	for _, field := range fields {
+		if field == "hashString" {
+			return fields
+		}
+	}
+
+	return append(append([]string(nil), fields...), "hashString")
+}
+
+// ids identifies the torrent in the rpc arguments.
+func (h *TorrentHandle) ids() interface{} {
+	if h.HashString != "" {
+		return []string{h.HashString}
+	}
+
+	return []int64{h.ID}
+}
+
+func (h *TorrentHandle) filter() Filter {
+	return Filter{Ids: h.ids()}
+}
+
+func (h *TorrentHandle) Start(ctx context.Context) error {
+	return h.client.TorrentStart(ctx, h.filter())
+}
+
+func (h *TorrentHandle) StartNow(ctx context.Context) error {
+	return h.client.TorrentStartNow(ctx, h.filter())
+}
+
+func (h *TorrentHandle) Stop(ctx context.Context) error {
+	return h.client.TorrentStop(ctx, h.filter())
+}
+
+func (h *TorrentHandle) Verify(ctx context.Context) error {
+	return h.client.TorrentVerify(ctx, h.filter())
+}
+
+func (h *TorrentHandle) Reannounce(ctx context.Context) error {
+	return h.client.TorrentReannounce(ctx, h.filter())
+}
+
+// Remove removes the torrent from the daemon, and its downloaded data when
+// deleteData is set.
+func (h *TorrentHandle) Remove(ctx context.Context, deleteData bool) error {
+	return h.client.TorrentRemove(ctx, TorrentRemove{Ids: h.ids(), DeleteLocalData: deleteData})
+}
+
+// Move sets the download dir of the torrent, moving the data already
+// downloaded when move is set.
+func (h *TorrentHandle) Move(ctx context.Context, location string, move bool) error {
+	if err := h.client.TorrentMove(ctx, TorrentMove{Ids: h.ids(), Location: location, Move: move}); err != nil {
+		return err
+	}
+
+	h.DownloadDir = location
+
+	return nil
+}
+
+// Rename renames the torrent, i.e. its top level file or directory. The
+// current name is fetched first when unknown.
+func (h *TorrentHandle) Rename(ctx context.Context, name string) error {
+	if h.Name == "" {
+		current := TorrentHandle{Torrent: Torrent{ID: h.ID, HashString: h.HashString}, client: h.client}
+		if err := current.Refresh(ctx, "name"); err != nil {
+			return err
+		}
+
+		h.Name = current.Name
+	}
+
+	if _, err := h.client.TorrentRename(ctx, TorrentRename{Ids: h.ids(), Path: h.Name, Name: name}); err != nil {
+		return err
+	}
+
+	h.Name = name
+
+	return nil
+}
+
+// SetLabels replaces the labels of the torrent, no labels clear them.
+func (h *TorrentHandle) SetLabels(ctx context.Context, labels ...string) error {
+	if labels == nil {
+		labels = []string{}
+	}
+
+	// TorrentSet always sends its numeric settings, which would reset them
+	_, err := h.client.fetch(ctx, request{
+		Method:    MethodTorrentSet,
+		Arguments: map[string]interface{}{"ids": h.ids(), "labels": labels},
+	})
+	if err != nil {
+		return err
+	}
+
+	h.Labels = labels
+
+	return nil
+}
+
+// Refresh fetches fields (DefaultHandleFields when empty) again and replaces
+// the torrent with the result. It returns ErrTorrentNotFound when the torrent
+// was removed.
+func (h *TorrentHandle) Refresh(ctx context.Context, fields ...string) error {
+	if len(fields) == 0 {
+		fields = DefaultHandleFields
+	}
+
+	torrents, err := h.client.TorrentGet(ctx, TorrentGet{Ids: h.ids(), Fields: withHash(fields)})
+	if err != nil {
+		return err
+	}
+
+	if len(torrents) == 0 {
+		return fmt.Errorf("%w: %s", ErrTorrentNotFound, h)
+	}
+
+	h.Torrent = torrents[0]
+
+	return nil
+}
+
+func (h *TorrentHandle) QueueMoveTop(ctx context.Context) error {
+	return h.client.QueueMoveTop(ctx, h.filter())
+}
+
+func (h *TorrentHandle) QueueMoveBottom(ctx context.Context) error {
+	return h.client.QueueMoveBottom(ctx, h.filter())
+}
+
+func (h *TorrentHandle) QueueMoveUp(ctx context.Context) error {
+	return h.client.QueueMoveUp(ctx, h.filter())
+}
+
+func (h *TorrentHandle) QueueMoveDown(ctx context.Context) error {
+	return h.client.QueueMoveDown(ctx, h.filter())
+}
+
+func (h *TorrentHandle) String() string {
+	if h.HashString != "" {
+		return h.HashString
+	}
+
+	return fmt.Sprintf("#%d", h.ID)
+}
@@ -0,0 +1,80 @@
+package transmission_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTorrentHandle(t *testing.T) {
+	ctx := context.Background()
+
+	s := transmissiontest.NewServer()
+	defer s.Close()
+
+	s.AddTorrent(transmission.Torrent{Name: "debian.iso", HashString: "aaaa", DownloadDir: "/downloads"})
+	s.AddTorrent(transmission.Torrent{Name: "ubuntu.iso", HashString: "bbbb", DownloadDir: "/downloads"})
+
+	client := s.Client()
+
+	t.Run("should request the hash with the fields", func(st *testing.T) {
+		handles, err := client.Handles(ctx, transmission.TorrentGet{Fields: []string{"id", "name"}})
+		assert.NoError(st, err)
+		assert.Len(st, handles, 2)
+		assert.Equal(st, "aaaa", handles[0].HashString)
+		assert.Equal(st, "debian.iso", handles[0].Name)
+	})
+
+	t.Run("should operate on the torrent by hash", func(st *testing.T) {
+		handles, _ := client.Handles(ctx, transmission.TorrentGet{Ids: []string{"bbbb"}, Fields: []string{"id"}})
+		handle := handles[0]
+
+		assert.NoError(st, handle.Start(ctx))
+		assert.NoError(st, handle.SetLabels(ctx, "linux", "iso"))
+		assert.NoError(st, handle.Move(ctx, "/media/iso", false))
+		assert.NoError(st, handle.Rename(ctx, "ubuntu-22.04.iso"))
+		assert.NoError(st, handle.QueueMoveTop(ctx))
+
+		assert.NoError(st, handle.Refresh(ctx))
+		assert.Equal(st, "ubuntu-22.04.iso", handle.Name)
+		assert.Equal(st, "/media/iso", handle.DownloadDir)
+		assert.Equal(st, []string{"linux", "iso"}, handle.Labels)
+		assert.Equal(st, transmission.StatusDownload, handle.Status)
+		assert.Equal(st, int64(0), handle.QueuePosition)
+
+		// the other torrent is untouched
+		other := s.Torrents()[1]
+		assert.Equal(st, "aaaa", other.HashString)
+		assert.Empty(st, other.Labels)
+
+		assert.NoError(st, handle.SetLabels(ctx))
+		assert.NoError(st, handle.Refresh(ctx, "labels"))
+		assert.Empty(st, handle.Labels)
+
+		assert.NoError(st, handle.Stop(ctx))
+		assert.NoError(st, handle.Refresh(ctx, "status"))
+		assert.Equal(st, transmission.StatusStopped, handle.Status)
+	})
+
+	t.Run("should fall back to the id without hash", func(st *testing.T) {
+		handle := client.Handle(transmission.Torrent{ID: 1})
+
+		assert.NoError(st, handle.Refresh(ctx, "name"))
+		assert.Equal(st, "debian.iso", handle.Name)
+		assert.Equal(st, "aaaa", handle.HashString)
+	})
+
+	t.Run("should report removed torrents", func(st *testing.T) {
+		handle := client.Handle(transmission.Torrent{HashString: "aaaa"})
+
+		assert.NoError(st, handle.Remove(ctx, true))
+		assert.Len(st, s.Torrents(), 1)
+
+		err := handle.Refresh(ctx)
+		assert.True(st, errors.Is(err, transmission.ErrTorrentNotFound))
+	})
+}
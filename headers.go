@@ -0,0 +1,82 @@
+package transmission
+
+import (
+	"context"
+	"net/http"
+)
+
+// HeaderProvider returns headers added to every rpc request. It is called
+// once per request, so it can be used for rotating tokens.
+type HeaderProvider func(ctx context.Context) (http.Header, error)
+
+// WithHeader adds a static header to every rpc request, e.g. an api key
+// expected by a proxy in front of the daemon.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Add(key, value)
+	}
+}
+
+// WithHeaderProvider sets a function returning headers for each rpc request.
+// Its headers replace the static ones with the same name.
+func WithHeaderProvider(provider HeaderProvider) Option {
+	return func(c *Client) {
+		c.headerProvider = provider
+	}
+}
+
+// WithBearerToken authenticates every rpc request with the given token
+// instead of basic auth. The last token set is the one sent.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithUserAgent identifies the application in the User-Agent header. The
+// library version is appended, e.g. "my-tool/1.2 transmission/0.1.0".
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		if userAgent == "" {
+			c.userAgent = DefaultUserAgent
+			return
+		}
+		c.userAgent = userAgent + " " + DefaultUserAgent
+	}
+}
+
+func (c *Client) setHeaders(ctx context.Context, header http.Header, sessionID string) error {
+	userAgent := c.userAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+
+	header.Set("User-Agent", userAgent)
+	header.Set("Content-Type", "application/json")
+
+	for key, values := range c.headers {
+		header[key] = append([]string(nil), values...)
+	}
+
+	if c.headerProvider != nil {
+		dynamic, err := c.headerProvider(ctx)
+		if err != nil {
+			return err
+		}
+
+		for key, values := range dynamic {
+			header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+	}
+
+	// the session id is handled by the client and can not be overridden
+	header.Set(SessionIDHeader, sessionID)
+
+	return nil
+}
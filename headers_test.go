@@ -0,0 +1,85 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Headers(t *testing.T) {
+	requestHeaders := func(opts ...Option) (http.Header, error) {
+		var header http.Header
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			_, _ = w.Write([]byte(`{"result": "success"}`))
+		}))
+		defer s.Close()
+
+		client, _ := New(append([]Option{WithURL(s.URL), WithHTTPClient(s.Client())}, opts...)...)
+		err := client.SessionClose(context.Background())
+
+		return header, err
+	}
+
+	t.Run("should send the default user agent", func(st *testing.T) {
+		header, err := requestHeaders()
+		assert.NoError(st, err)
+		assert.Equal(st, "transmission/"+Version, header.Get("User-Agent"))
+	})
+
+	t.Run("should append library version to custom user agent", func(st *testing.T) {
+		header, err := requestHeaders(WithUserAgent("my-tool/1.2"))
+		assert.NoError(st, err)
+		assert.Equal(st, "my-tool/1.2 transmission/"+Version, header.Get("User-Agent"))
+	})
+
+	t.Run("should send static headers", func(st *testing.T) {
+		header, err := requestHeaders(WithHeader("X-Api-Key", "key"), WithHeader("X-Tags", "a"), WithHeader("X-Tags", "b"))
+		assert.NoError(st, err)
+		assert.Equal(st, "key", header.Get("X-Api-Key"))
+		assert.Equal(st, []string{"a", "b"}, header["X-Tags"])
+	})
+
+	t.Run("should send bearer token instead of basic auth", func(st *testing.T) {
+		header, err := requestHeaders(WithBasicAuth("user", "secret"), WithBearerToken("token"))
+		assert.NoError(st, err)
+		assert.Equal(st, "Bearer token", header.Get("Authorization"))
+	})
+
+	t.Run("should send only the last bearer token", func(st *testing.T) {
+		header, err := requestHeaders(WithBearerToken("old"), WithBearerToken("new"))
+		assert.NoError(st, err)
+		assert.Equal(st, []string{"Bearer new"}, header["Authorization"])
+	})
+
+	t.Run("should call header provider on each request", func(st *testing.T) {
+		calls := 0
+		provider := func(ctx context.Context) (http.Header, error) {
+			calls++
+			return http.Header{"x-token": []string{"rotated"}}, nil
+		}
+
+		header, err := requestHeaders(WithHeader("X-Token", "static"), WithHeaderProvider(provider))
+		assert.NoError(st, err)
+		assert.Equal(st, 1, calls)
+		assert.Equal(st, []string{"rotated"}, header["X-Token"])
+	})
+
+	t.Run("should not override the session id header", func(st *testing.T) {
+		header, err := requestHeaders(WithHeader(SessionIDHeader, "forged"))
+		assert.NoError(st, err)
+		assert.Equal(st, "", header.Get(SessionIDHeader))
+	})
+
+	t.Run("should return header provider errors", func(st *testing.T) {
+		providerErr := errors.New("token expired")
+		_, err := requestHeaders(WithHeaderProvider(func(ctx context.Context) (http.Header, error) {
+			return nil, providerErr
+		}))
+		assert.True(st, errors.Is(err, providerErr))
+	})
+}
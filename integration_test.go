@@ -0,0 +1,63 @@
+package transmission_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegration_TorrentLifecycle(t *testing.T) {
+	s := transmissiontest.NewServer(transmissiontest.WithCredentials("admin", "secret"))
+	defer s.Close()
+
+	ctx := context.Background()
+	client := s.Client()
+
+	added, err := client.TorrentAdd(ctx, transmission.TorrentAdd{
+		Filename: "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=debian.iso",
+		Paused:   true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "debian.iso", added.Name)
+
+	filter := transmission.Filter{Ids: []string{added.HashString}}
+	assert.NoError(t, client.TorrentStart(ctx, filter))
+
+	torrents, err := client.TorrentGet(ctx, transmission.TorrentGet{
+		Ids:    []string{added.HashString},
+		Fields: []string{"id", "name", "status"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, torrents, 1)
+	assert.Equal(t, int64(4), torrents[0].Status)
+
+	stats, err := client.SessionStats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.ActiveTorrentCount)
+
+	assert.NoError(t, client.TorrentRemove(ctx, transmission.TorrentRemove{Ids: filter.Ids}))
+	assert.Empty(t, s.Torrents())
+}
+
+func TestIntegration_Failover(t *testing.T) {
+	lan := transmissiontest.NewServer(transmissiontest.WithSessionID("lan"))
+	vpn := transmissiontest.NewServer(transmissiontest.WithSessionID("vpn"))
+	defer vpn.Close()
+
+	client, err := transmission.New(transmission.WithEndpoints(lan.URL, vpn.URL))
+	assert.NoError(t, err)
+
+	_, err = client.SessionGet(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, lan.URL+transmission.DefaultRPCPath, client.ActiveEndpoint())
+
+	lan.Close()
+
+	_, err = client.SessionGet(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, vpn.URL+transmission.DefaultRPCPath, client.ActiveEndpoint())
+	assert.Equal(t, "vpn", client.SessionID)
+}
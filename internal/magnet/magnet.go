@@ -0,0 +1,201 @@
+// Package magnet parses magnet links. It is shared by the client AddMagnet
+// and metainfo.ParseMagnet, so both accept the same links.
+package magnet
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxSelect bounds the file indices of a "so" parameter.
+	MaxSelect = 1 << 16
+
+	BtihPrefix = "urn:btih:"
+	BtmhPrefix = "urn:btmh:"
+	// BtmhHeader is the sha2-256 multihash code and digest length.
+	BtmhHeader = "1220"
+)
+
+var (
+	ErrInvalid = errors.New("invalid magnet link")
+)
+
+// Link is a parsed magnet link, see metainfo.Magnet for the fields.
+type Link struct {
+	InfoHash   string
+	InfoHashV2 string
+	Name       string
+	Length     int64
+	Trackers   []string
+	WebSeeds   []string
+	Select     []int64
+	Peers      []string
+}
+
+// Parse parses a magnet link, normalizing its hashes to lowercase hex.
+func Parse(uri string) (Link, error) {
+	var l Link
+
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "magnet" {
+		return l, fmt.Errorf("%w: %s", ErrInvalid, uri)
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return l, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	for _, xt := range query["xt"] {
+		if err := l.parseTopic(xt); err != nil {
+			return Link{}, err
+		}
+	}
+
+	if l.InfoHash == "" && l.InfoHashV2 == "" {
+		return Link{}, fmt.Errorf("%w: missing info hash in %s", ErrInvalid, uri)
+	}
+
+	l.Name = query.Get("dn")
+	l.Trackers = nonEmpty(query["tr"])
+	l.WebSeeds = nonEmpty(query["ws"])
+
+	if xl := query.Get("xl"); xl != "" {
+		if l.Length, err = strconv.ParseInt(xl, 10, 64); err != nil || l.Length < 0 {
+			return Link{}, fmt.Errorf("%w: invalid length %q", ErrInvalid, xl)
+		}
+	}
+
+	if so := query.Get("so"); so != "" {
+		if l.Select, err = parseSelect(so); err != nil {
+			return Link{}, err
+		}
+	}
+
+	for _, peer := range query["x.pe"] {
+		if _, _, err := net.SplitHostPort(peer); err != nil {
+			return Link{}, fmt.Errorf("%w: invalid peer %q", ErrInvalid, peer)
+		}
+		l.Peers = append(l.Peers, peer)
+	}
+
+	return l, nil
+}
+
+func (l *Link) parseTopic(xt string) error {
+	var hash *string
+	var value string
+
+	switch {
+	case strings.HasPrefix(xt, BtihPrefix):
+		hash = &l.InfoHash
+		value = decodeBtih(strings.TrimPrefix(xt, BtihPrefix))
+	case strings.HasPrefix(xt, BtmhPrefix):
+		hash = &l.InfoHashV2
+		value = decodeBtmh(strings.TrimPrefix(xt, BtmhPrefix))
+	default:
+		// other networks' topics
+		return nil
+	}
+
+	if value == "" {
+		return fmt.Errorf("%w: invalid exact topic %q", ErrInvalid, xt)
+	}
+
+	if *hash != "" && *hash != value {
+		return fmt.Errorf("%w: conflicting exact topics", ErrInvalid)
+	}
+	*hash = value
+
+	return nil
+}
+
+// decodeBtih returns the hex form of a hex or base32 v1 hash, or "".
+func decodeBtih(hash string) string {
+	var buf []byte
+	var err error
+
+	switch len(hash) {
+	case hex.EncodedLen(sha1.Size):
+		buf, err = hex.DecodeString(hash)
+	case base32.StdEncoding.EncodedLen(sha1.Size):
+		buf, err = base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+	default:
+		return ""
+	}
+
+	if err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+// decodeBtmh returns the hex digest of a sha2-256 multihash, or "".
+func decodeBtmh(hash string) string {
+	if len(hash) != len(BtmhHeader)+hex.EncodedLen(sha256.Size) || !strings.HasPrefix(hash, BtmhHeader) {
+		return ""
+	}
+
+	buf, err := hex.DecodeString(hash[len(BtmhHeader):])
+	if err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+// parseSelect parses a list of indices and ranges, e.g. "0,2,4-6".
+func parseSelect(so string) ([]int64, error) {
+	var indices []int64
+	seen := make(map[int64]bool)
+
+	for _, item := range strings.Split(so, ",") {
+		from, to := item, item
+		if dash := strings.IndexByte(item, '-'); dash >= 0 {
+			from, to = item[:dash], item[dash+1:]
+		}
+
+		start, err1 := strconv.ParseInt(from, 10, 64)
+		end, err2 := strconv.ParseInt(to, 10, 64)
+		if err1 != nil || err2 != nil || start < 0 || end < start || end-start >= MaxSelect {
+			return nil, fmt.Errorf("%w: invalid file selection %q", ErrInvalid, item)
+		}
+
+		for i := start; i <= end; i++ {
+			if seen[i] {
+				continue
+			}
+			if len(indices) == MaxSelect {
+				return nil, fmt.Errorf("%w: more than %d selected files", ErrInvalid, MaxSelect)
+			}
+			seen[i] = true
+			indices = append(indices, i)
+		}
+	}
+
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	return indices, nil
+}
+
+func nonEmpty(values []string) []string {
+	var list []string
+	for _, value := range values {
+		if value != "" {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,260 @@
+package metainfo
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfuentesg/transmission/bencode"
+)
+
+const (
+	MinPieceLength = 16 << 10
+	MaxPieceLength = 16 << 20
+)
+
+var (
+	ErrNoFiles            = errors.New("no files to create the torrent from")
+	ErrInvalidPieceLength = errors.New("invalid piece length")
+)
+
+// CreateOptions configures Create. The zero value creates a public torrent
+// without trackers, with a piece length chosen from the data size.
+type CreateOptions struct {
+	// PieceLength must be a power of two between MinPieceLength and
+	// MaxPieceLength, it is chosen from the data size when 0.
+	PieceLength int64
+	// Name defaults to the base name of the path, the daemon only finds the
+	// existing data under that name.
+	Name    string
+	Private bool
+	// Trackers are the announce tiers, the first tracker being used as
+	// announce url.
+	Trackers  [][]string
+	WebSeeds  []string
+	Comment   string
+	Source    string
+	CreatedBy string
+	// CreationDate defaults to the current time.
+	CreationDate time.Time
+	// Workers hashing the pieces, runtime.NumCPU() when 0.
+	Workers int
+	// Progress is called after each hashed piece.
+	Progress func(done, total int)
+}
+
+type createFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type createInfo struct {
+	Files       []createFile `bencode:"files,omitempty"`
+	Length      int64        `bencode:"length,omitempty"`
+	Name        string       `bencode:"name"`
+	PieceLength int64        `bencode:"piece length"`
+	Pieces      []byte       `bencode:"pieces"`
+	Private     bool         `bencode:"private,omitempty"`
+	Source      string       `bencode:"source,omitempty"`
+}
+
+type createMetainfo struct {
+	Announce     string             `bencode:"announce,omitempty"`
+	AnnounceList [][]string         `bencode:"announce-list,omitempty"`
+	Comment      string             `bencode:"comment,omitempty"`
+	CreatedBy    string             `bencode:"created by,omitempty"`
+	CreationDate int64              `bencode:"creation date,omitempty"`
+	Info         bencode.RawMessage `bencode:"info"`
+	URLList      []string           `bencode:"url-list,omitempty"`
+}
+
+// Create builds a .torrent of the file or directory at path, ready for
+// AddReader or TorrentAdd.MetaInfo. Adding it with the parent directory of
+// path as download dir seeds the existing data.
+func Create(ctx context.Context, path string, opts CreateOptions) ([]byte, error) {
+	path = filepath.Clean(path)
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info := createInfo{Name: opts.Name, Private: opts.Private, Source: opts.Source}
+	if info.Name == "" {
+		info.Name = filepath.Base(path)
+	}
+
+	var files []File
+	dir := path
+
+	if stat.IsDir() {
+		if files, err = walk(path); err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			info.Files = append(info.Files, createFile{Length: f.Length, Path: f.Path})
+		}
+	} else {
+		dir = filepath.Dir(path)
+		files = []File{{Path: []string{filepath.Base(path)}, Length: stat.Size()}}
+		info.Length = stat.Size()
+	}
+
+	var size int64
+	for _, f := range files {
+		size += f.Length
+	}
+
+	if size == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoFiles, path)
+	}
+
+	info.PieceLength = opts.PieceLength
+	if info.PieceLength == 0 {
+		info.PieceLength = PieceLength(size)
+	}
+
+	if l := info.PieceLength; l < MinPieceLength || l > MaxPieceLength || l&(l-1) != 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPieceLength, l)
+	}
+
+	s := newStorage(dir, files, info.PieceLength)
+	if info.Pieces, err = hashPieces(ctx, s, opts); err != nil {
+		return nil, err
+	}
+
+	raw, err := bencode.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	m := createMetainfo{
+		AnnounceList: nonEmptyTiers(opts.Trackers),
+		Comment:      opts.Comment,
+		CreatedBy:    opts.CreatedBy,
+		CreationDate: opts.CreationDate.Unix(),
+		Info:         raw,
+		URLList:      opts.WebSeeds,
+	}
+
+	if opts.CreationDate.IsZero() {
+		m.CreationDate = time.Now().Unix()
+	}
+
+	if len(m.AnnounceList) > 0 {
+		m.Announce = m.AnnounceList[0][0]
+	}
+
+	// a single tracker does not need a list
+	if len(m.AnnounceList) == 1 && len(m.AnnounceList[0]) == 1 {
+		m.AnnounceList = nil
+	}
+
+	return bencode.Marshal(m)
+}
+
+// PieceLength returns the piece length Create uses for size bytes of data,
+// the same transmission-create chooses.
+func PieceLength(size int64) int64 {
+	const mib = 1 << 20
+
+	switch {
+	case size >= 2048*mib:
+		return 2 * mib
+	case size >= 1024*mib:
+		return mib
+	case size >= 512*mib:
+		return mib / 2
+	case size >= 350*mib:
+		return mib / 4
+	case size >= 150*mib:
+		return mib / 8
+	case size >= 50*mib:
+		return mib / 16
+	default:
+		return mib / 32
+	}
+}
+
+// walk lists the regular files under dir, in lexical order.
+func walk(dir string) ([]File, error) {
+	var files []File
+	var offset int64
+
+	err := filepath.Walk(dir, func(path string, stat os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !stat.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, File{Path: strings.Split(filepath.ToSlash(rel), "/"), Length: stat.Size(), Offset: offset})
+		offset += stat.Size()
+
+		return nil
+	})
+
+	return files, err
+}
+
+func hashPieces(ctx context.Context, s *storage, opts CreateOptions) ([]byte, error) {
+	count := s.pieceCount()
+	pieces := make([]byte, count*sha1.Size)
+
+	var mu sync.Mutex
+	done := 0
+
+	err := eachPiece(ctx, count, opts.Workers, func(index int) error {
+		hash, err := s.hashPiece(index)
+		if err != nil {
+			return err
+		}
+		copy(pieces[index*sha1.Size:], hash[:])
+
+		if opts.Progress != nil {
+			mu.Lock()
+			done++
+			opts.Progress(done, count)
+			mu.Unlock()
+		}
+
+		return nil
+	})
+
+	return pieces, err
+}
+
+func nonEmptyTiers(tiers [][]string) [][]string {
+	var list [][]string
+	for _, tier := range tiers {
+		if trackers := nonEmpty(tier); len(trackers) > 0 {
+			list = append(list, trackers)
+		}
+	}
+
+	return list
+}
+
+func nonEmpty(values []string) []string {
+	var list []string
+	for _, value := range values {
+		if value != "" {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
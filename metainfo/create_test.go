@@ -0,0 +1,174 @@
+package metainfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFiles creates the files under dir, returning their concatenated
+// content.
+func writeFiles(t *testing.T, dir string, files map[string]int) []byte {
+	var data []byte
+
+	for _, name := range sortedKeys(files) {
+		content := bytes.Repeat([]byte(name), files[name]/len(name)+1)[:files[name]]
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		data = append(data, content...)
+	}
+
+	return data
+}
+
+func sortedKeys(files map[string]int) []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func pieceHashes(data []byte, pieceLength int) []byte {
+	var pieces []byte
+	for start := 0; start < len(data); start += pieceLength {
+		end := start + pieceLength
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha1.Sum(data[start:end])
+		pieces = append(pieces, sum[:]...)
+	}
+
+	return pieces
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "metainfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "dataset")
+	data := writeFiles(t, root, map[string]int{
+		"a/1.csv":    40000,
+		"a/2.csv":    10,
+		"b.txt":      70000,
+		"empty.txt":  0,
+		"z/y/x.json": 5,
+	})
+
+	t.Run("should create multi file torrents", func(st *testing.T) {
+		date := time.Unix(1600000000, 0).UTC()
+		progress := 0
+
+		buf, err := Create(ctx, root, CreateOptions{
+			Private:      true,
+			Trackers:     [][]string{{"http://a/announce", ""}, {"udp://b:80", "udp://c:80"}, {}},
+			WebSeeds:     []string{"http://mirror/"},
+			Comment:      "dataset",
+			Source:       "INT",
+			CreatedBy:    "tests",
+			CreationDate: date,
+			Workers:      3,
+			Progress:     func(done, total int) { progress = done },
+		})
+		assert.NoError(st, err)
+
+		m, err := Parse(buf)
+		assert.NoError(st, err)
+		assert.Equal(st, "dataset", m.Name)
+		assert.Equal(st, int64(32<<10), m.PieceLength)
+		assert.Equal(st, pieceHashes(data, 32<<10), m.Pieces)
+		assert.Equal(st, 4, progress)
+		assert.Equal(st, []File{
+			{Path: []string{"a", "1.csv"}, Length: 40000},
+			{Path: []string{"a", "2.csv"}, Length: 10, Offset: 40000},
+			{Path: []string{"b.txt"}, Length: 70000, Offset: 40010},
+			{Path: []string{"empty.txt"}, Offset: 110010},
+			{Path: []string{"z", "y", "x.json"}, Length: 5, Offset: 110010},
+		}, m.Files)
+		assert.True(st, m.Private)
+		assert.Equal(st, "INT", m.Source)
+		assert.Equal(st, "http://a/announce", m.Announce)
+		assert.Equal(st, [][]string{{"http://a/announce"}, {"udp://b:80", "udp://c:80"}}, m.AnnounceList)
+		assert.Equal(st, []string{"http://mirror/"}, m.WebSeeds)
+		assert.Equal(st, "dataset", m.Comment)
+		assert.Equal(st, "tests", m.CreatedBy)
+		assert.Equal(st, date, m.CreationDate)
+	})
+
+	t.Run("should create single file torrents", func(st *testing.T) {
+		buf, err := Create(ctx, filepath.Join(root, "b.txt"), CreateOptions{
+			PieceLength: 16 << 10,
+			Name:        "renamed.txt",
+			Trackers:    [][]string{{"http://a/announce"}},
+		})
+		assert.NoError(st, err)
+
+		m, err := Parse(buf)
+		assert.NoError(st, err)
+		assert.Equal(st, "renamed.txt", m.Name)
+		assert.Equal(st, []File{{Path: []string{"renamed.txt"}, Length: 70000}}, m.Files)
+		assert.Equal(st, pieceHashes(data[40010:110010], 16<<10), m.Pieces)
+		assert.Equal(st, "http://a/announce", m.Announce)
+		assert.Nil(st, m.AnnounceList)
+		assert.False(st, m.Private)
+		assert.WithinDuration(st, time.Now(), m.CreationDate, time.Minute)
+	})
+
+	t.Run("should reject invalid piece lengths", func(st *testing.T) {
+		for _, length := range []int64{1 << 10, 3 << 14, 32 << 20} {
+			_, err := Create(ctx, root, CreateOptions{PieceLength: length})
+			assert.True(st, errors.Is(err, ErrInvalidPieceLength), "%d", length)
+		}
+	})
+
+	t.Run("should reject empty data", func(st *testing.T) {
+		_, err := Create(ctx, filepath.Join(root, "empty.txt"), CreateOptions{})
+		assert.True(st, errors.Is(err, ErrNoFiles))
+	})
+
+	t.Run("should fail for missing paths", func(st *testing.T) {
+		_, err := Create(ctx, filepath.Join(dir, "missing"), CreateOptions{})
+		assert.True(st, os.IsNotExist(err))
+	})
+
+	t.Run("should stop when the context is done", func(st *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := Create(ctx, root, CreateOptions{})
+		assert.Equal(st, context.Canceled, err)
+	})
+}
+
+func TestPieceLength(t *testing.T) {
+	const mib = 1 << 20
+
+	assert.Equal(t, int64(32<<10), PieceLength(1))
+	assert.Equal(t, int64(64<<10), PieceLength(50*mib))
+	assert.Equal(t, int64(256<<10), PieceLength(400*mib))
+	assert.Equal(t, int64(2*mib), PieceLength(8192*mib))
+}
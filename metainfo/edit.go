@@ -0,0 +1,199 @@
+package metainfo
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/mfuentesg/transmission/bencode"
+)
+
+// Editor changes the fields of a .torrent file, keeping the keys it does not
+// know as they are. The info dictionary is only encoded again when one of its
+// fields is set, so the other changes keep the infohash.
+type Editor struct {
+	// root and info hold the raw values of the file, or the ones set since
+	root      map[string]interface{}
+	info      map[string]interface{}
+	original  bencode.RawMessage
+	infoDirty bool
+}
+
+// NewEditor parses the content of a .torrent file for editing.
+func NewEditor(data []byte) (*Editor, error) {
+	if _, err := Parse(data); err != nil {
+		return nil, err
+	}
+
+	var root, info map[string]bencode.RawMessage
+	if err := bencode.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetainfo, err)
+	}
+
+	if err := bencode.Unmarshal(root["info"], &info); err != nil {
+		return nil, fmt.Errorf("%w: info: %v", ErrInvalidMetainfo, err)
+	}
+
+	e := Editor{
+		root:     make(map[string]interface{}, len(root)),
+		info:     make(map[string]interface{}, len(info)),
+		original: root["info"],
+	}
+
+	for key, value := range root {
+		e.root[key] = value
+	}
+	for key, value := range info {
+		e.info[key] = value
+	}
+
+	return &e, nil
+}
+
+func (e *Editor) setInfo(key string, value interface{}) {
+	e.info[key] = value
+	e.infoDirty = true
+}
+
+func (e *Editor) deleteInfo(key string) {
+	if _, ok := e.info[key]; ok {
+		delete(e.info, key)
+		e.infoDirty = true
+	}
+}
+
+// get decodes the value of key into target, returning false when it is
+// missing or of another type.
+func (e *Editor) get(key string, target interface{}) bool {
+	value, ok := e.root[key]
+	if !ok {
+		return false
+	}
+
+	buf, err := bencode.Marshal(value)
+	return err == nil && bencode.Unmarshal(buf, target) == nil
+}
+
+// Trackers returns the announce tiers, the announce url being the only tier
+// of torrents without announce list.
+func (e *Editor) Trackers() [][]string {
+	var tiers [][]string
+	if e.get("announce-list", &tiers) && len(nonEmptyTiers(tiers)) > 0 {
+		return nonEmptyTiers(tiers)
+	}
+
+	var announce string
+	if e.get("announce", &announce) && announce != "" {
+		return [][]string{{announce}}
+	}
+
+	return nil
+}
+
+// SetTrackers replaces the announce url and list with the tiers, the first
+// tracker being the announce url. It removes them when tiers is empty.
+func (e *Editor) SetTrackers(tiers [][]string) {
+	tiers = nonEmptyTiers(tiers)
+
+	delete(e.root, "announce")
+	delete(e.root, "announce-list")
+
+	if len(tiers) == 0 {
+		return
+	}
+
+	e.root["announce"] = tiers[0][0]
+
+	// a single tracker does not need a list
+	if len(tiers) > 1 || len(tiers[0]) > 1 {
+		e.root["announce-list"] = tiers
+	}
+}
+
+// StripTrackers removes the announce url and list, leaving DHT and PEX to
+// find peers.
+func (e *Editor) StripTrackers() {
+	e.SetTrackers(nil)
+}
+
+// SetWebSeeds replaces the web seeds, removing them when seeds is empty.
+func (e *Editor) SetWebSeeds(seeds []string) {
+	if seeds = nonEmpty(seeds); len(seeds) == 0 {
+		delete(e.root, "url-list")
+		return
+	}
+
+	e.root["url-list"] = seeds
+}
+
+// SetComment replaces the comment, removing it when empty.
+func (e *Editor) SetComment(comment string) {
+	if comment == "" {
+		delete(e.root, "comment")
+		return
+	}
+
+	e.root["comment"] = comment
+}
+
+// SetPrivate sets or clears the private flag, which changes the infohash.
+func (e *Editor) SetPrivate(private bool) {
+	if !private {
+		e.deleteInfo("private")
+		return
+	}
+
+	e.setInfo("private", 1)
+}
+
+// SetSource sets the source tag, which changes the infohash, so the same data
+// can be seeded on trackers rejecting each other's torrents. An empty source
+// removes the tag.
+func (e *Editor) SetSource(source string) {
+	if source == "" {
+		e.deleteInfo("source")
+		return
+	}
+
+	e.setInfo("source", source)
+}
+
+func (e *Editor) infoBytes() (bencode.RawMessage, error) {
+	if !e.infoDirty {
+		return e.original, nil
+	}
+
+	return bencode.Marshal(e.info)
+}
+
+// InfoHashChanged reports whether the edits change the infohash, i.e. the
+// daemon and the trackers will consider the result another torrent.
+func (e *Editor) InfoHashChanged() bool {
+	info, err := e.infoBytes()
+	return err != nil || !bytes.Equal(info, e.original)
+}
+
+// Bytes returns the edited .torrent file.
+func (e *Editor) Bytes() ([]byte, error) {
+	info, err := e.infoBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	root := make(map[string]interface{}, len(e.root))
+	for key, value := range e.root {
+		root[key] = value
+	}
+	root["info"] = info
+
+	return bencode.Marshal(root)
+}
+
+// Metainfo parses the edited .torrent file.
+func (e *Editor) Metainfo() (*Metainfo, error) {
+	buf, err := e.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(buf)
+}
@@ -0,0 +1,105 @@
+package metainfo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mfuentesg/transmission/bencode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditor(t *testing.T) {
+	// unsorted info keys and unknown keys must survive the edits
+	info := "d6:lengthi5e4:name3:abc6:pieces20:aaaaaaaaaaaaaaaaaaaa12:piece lengthi1e7:unknown1:xe"
+	data := []byte("d8:announce8:http://t7:comment3:old4:info" + info + "8:x-customli1eee")
+
+	parse := func(t *testing.T) (*Editor, *Metainfo) {
+		e, err := NewEditor(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := Parse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return e, m
+	}
+
+	t.Run("should keep the file as it is without edits", func(st *testing.T) {
+		e, _ := parse(st)
+
+		buf, err := e.Bytes()
+		assert.NoError(st, err)
+		assert.Equal(st, "d8:announce8:http://t7:comment3:old4:info"+info+"8:x-customli1eee", string(buf))
+		assert.False(st, e.InfoHashChanged())
+		assert.Equal(st, [][]string{{"http://t"}}, e.Trackers())
+	})
+
+	t.Run("should edit trackers, web seeds and comment keeping the infohash", func(st *testing.T) {
+		e, original := parse(st)
+
+		e.SetTrackers([][]string{{"http://a", ""}, {"http://b"}, nil})
+		e.SetWebSeeds([]string{"http://mirror/"})
+		e.SetComment("")
+
+		assert.False(st, e.InfoHashChanged())
+		assert.Equal(st, [][]string{{"http://a"}, {"http://b"}}, e.Trackers())
+
+		m, err := e.Metainfo()
+		assert.NoError(st, err)
+		assert.Equal(st, original.HashString(), m.HashString())
+		assert.Equal(st, "http://a", m.Announce)
+		assert.Equal(st, [][]string{{"http://a"}, {"http://b"}}, m.AnnounceList)
+		assert.Equal(st, []string{"http://mirror/"}, m.WebSeeds)
+		assert.Equal(st, "", m.Comment)
+
+		buf, _ := e.Bytes()
+		var raw map[string]bencode.RawMessage
+		assert.NoError(st, bencode.Unmarshal(buf, &raw))
+		assert.Equal(st, "li1ee", string(raw["x-custom"]))
+	})
+
+	t.Run("should strip trackers", func(st *testing.T) {
+		e, _ := parse(st)
+		e.StripTrackers()
+
+		m, err := e.Metainfo()
+		assert.NoError(st, err)
+		assert.Nil(st, m.Trackers())
+		assert.Nil(st, e.Trackers())
+		assert.False(st, e.InfoHashChanged())
+	})
+
+	t.Run("should change the infohash with the private flag and source", func(st *testing.T) {
+		e, original := parse(st)
+
+		e.SetPrivate(true)
+		e.SetSource("XYZ")
+		assert.True(st, e.InfoHashChanged())
+
+		m, err := e.Metainfo()
+		assert.NoError(st, err)
+		assert.True(st, m.Private)
+		assert.Equal(st, "XYZ", m.Source)
+		assert.NotEqual(st, original.HashString(), m.HashString())
+
+		var fields map[string]interface{}
+		assert.NoError(st, bencode.Unmarshal(m.Info, &fields))
+		assert.Equal(st, "x", fields["unknown"])
+	})
+
+	t.Run("should not change the infohash clearing missing fields", func(st *testing.T) {
+		e, _ := parse(st)
+
+		e.SetPrivate(false)
+		e.SetSource("")
+		assert.False(st, e.InfoHashChanged())
+	})
+
+	t.Run("should reject invalid metainfo", func(st *testing.T) {
+		_, err := NewEditor([]byte("d4:infod4:name1:aee"))
+		assert.True(st, errors.Is(err, ErrInvalidMetainfo))
+	})
+}
@@ -0,0 +1,48 @@
+//go:build go1.18
+// +build go1.18
+
+package metainfo
+
+import (
+	"testing"
+)
+
+func FuzzParseMagnet(f *testing.F) {
+	f.Add("magnet:?xt=urn:btih:" + hexHash + "&dn=debian&tr=http%3A%2F%2Fa%2F&so=0,2-3&x.pe=1.2.3.4:1")
+	f.Add("magnet:?xt=urn:btih:" + base32Hash)
+	f.Add("magnet:?xt=urn:btmh:1220" + v2Hash + "&xl=10&ws=http://m/")
+
+	f.Fuzz(func(t *testing.T, uri string) {
+		m, err := ParseMagnet(uri)
+		if err != nil {
+			return
+		}
+
+		// the canonical link must parse back to itself
+		canonical := m.String()
+		again, err := ParseMagnet(canonical)
+		if err != nil {
+			t.Fatalf("could not parse %q built from %q: %v", canonical, uri, err)
+		}
+
+		if again.String() != canonical {
+			t.Fatalf("%q built %q", canonical, again.String())
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add([]byte("d4:infod6:lengthi5e4:name3:abc12:piece lengthi1e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"))
+	f.Add([]byte("d4:infod9:file treed1:ad0:d6:lengthi1eeee12:meta versioni2e4:name1:a12:piece lengthi1eee"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := Parse(data)
+		if err != nil {
+			return
+		}
+
+		if m.TotalSize() < 0 || m.HashString() == "" {
+			t.Fatalf("invalid metainfo parsed from %q", data)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package metainfo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/internal/magnet"
+)
+
+const (
+	// MaxMagnetSelect bounds the file indices of a magnet "so" parameter.
+	MaxMagnetSelect = magnet.MaxSelect
+)
+
+var (
+	// ErrInvalidMagnet is the error of AddMagnet, so both can be matched
+	// with errors.Is.
+	ErrInvalidMagnet = transmission.ErrInvalidMagnet
+)
+
+// Magnet is a magnet link. InfoHash is the hex v1 hash and InfoHashV2 the hex
+// SHA-256 v2 hash, at least one of them being set.
+type Magnet struct {
+	InfoHash   string
+	InfoHashV2 string
+	// Name is the display name (dn).
+	Name string
+	// Length is the exact length (xl), 0 when unknown.
+	Length   int64
+	Trackers []string
+	WebSeeds []string
+	// Select are the indices of the files to download (so), all of them
+	// when empty.
+	Select []int64
+	// Peers are host:port addresses of peers (x.pe).
+	Peers []string
+}
+
+// ParseMagnet parses a magnet link, normalizing its hashes to lowercase hex
+// as the daemon reports them in Torrent.HashString. It accepts the same links
+// as AddMagnet.
+func ParseMagnet(uri string) (Magnet, error) {
+	link, err := magnet.Parse(uri)
+	if err != nil {
+		return Magnet{}, err
+	}
+
+	return Magnet(link), nil
+}
+
+func formatSelect(indices []int64) string {
+	sorted := append([]int64(nil), indices...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var ranges []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+
+		if sorted[i] == sorted[j] {
+			ranges = append(ranges, strconv.FormatInt(sorted[i], 10))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+
+	return strings.Join(ranges, ",")
+}
+
+// String builds the canonical link: topics first, then dn, xl, tr, ws, so and
+// x.pe, the values being percent-encoded.
+func (m Magnet) String() string {
+	var params []string
+
+	add := func(key, value string) {
+		params = append(params, key+"="+strings.ReplaceAll(url.QueryEscape(value), "+", "%20"))
+	}
+
+	if m.InfoHash != "" {
+		params = append(params, "xt="+magnet.BtihPrefix+strings.ToLower(m.InfoHash))
+	}
+	if m.InfoHashV2 != "" {
+		params = append(params, "xt="+magnet.BtmhPrefix+magnet.BtmhHeader+strings.ToLower(m.InfoHashV2))
+	}
+	if m.Name != "" {
+		add("dn", m.Name)
+	}
+	if m.Length > 0 {
+		add("xl", strconv.FormatInt(m.Length, 10))
+	}
+	for _, tracker := range m.Trackers {
+		add("tr", tracker)
+	}
+	for _, seed := range m.WebSeeds {
+		add("ws", seed)
+	}
+	if len(m.Select) > 0 {
+		params = append(params, "so="+formatSelect(m.Select))
+	}
+	for _, peer := range m.Peers {
+		add("x.pe", peer)
+	}
+
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// HashString returns the hash the daemon reports in Torrent.HashString, see
+// Metainfo.HashString.
+func (m Magnet) HashString() string {
+	if m.InfoHash != "" {
+		return strings.ToLower(m.InfoHash)
+	}
+
+	if len(m.InfoHashV2) < hex.EncodedLen(sha1.Size) {
+		return ""
+	}
+
+	return strings.ToLower(m.InfoHashV2[:hex.EncodedLen(sha1.Size)])
+}
+
+// Matches reports whether t is the torrent of the link.
+func (m Magnet) Matches(t transmission.Torrent) bool {
+	hash := m.HashString()
+	return hash != "" && strings.EqualFold(t.HashString, hash)
+}
+
+// Magnet returns the magnet link of the torrent.
+func (m *Metainfo) Magnet() Magnet {
+	link := Magnet{
+		Name:     m.Name,
+		Length:   m.TotalSize(),
+		Trackers: m.Trackers(),
+		WebSeeds: m.WebSeeds,
+	}
+
+	if m.v1 {
+		link.InfoHash = hex.EncodeToString(m.InfoHash[:])
+	}
+	if m.v2 {
+		link.InfoHashV2 = hex.EncodeToString(m.InfoHashV2[:])
+	}
+
+	return link
+}
@@ -0,0 +1,127 @@
+package metainfo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	hexHash    = "0123456789abcdef0123456789abcdef01234567"
+	base32Hash = "AERUKZ4JVPG66AJDIVTYTK6N54ASGRLH"
+	v2Hash     = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+)
+
+func TestParseMagnet(t *testing.T) {
+	t.Run("should parse all the parameters", func(st *testing.T) {
+		uri := "magnet:?xt=urn:btih:" + strings.ToUpper(hexHash) + "&xt=urn:btmh:1220" + v2Hash +
+			"&dn=debian+11.iso&xl=1024&tr=http%3A%2F%2Fa%2Fannounce&tr=udp%3A%2F%2Fb%3A80" +
+			"&ws=http%3A%2F%2Fmirror%2F&so=4-6,0,2,5&x.pe=10.0.0.1:51413&x.pe=[::1]:6881&xt=urn:ed2k:abc"
+
+		m, err := ParseMagnet(uri)
+		assert.NoError(st, err)
+		assert.Equal(st, Magnet{
+			InfoHash:   hexHash,
+			InfoHashV2: v2Hash,
+			Name:       "debian 11.iso",
+			Length:     1024,
+			Trackers:   []string{"http://a/announce", "udp://b:80"},
+			WebSeeds:   []string{"http://mirror/"},
+			Select:     []int64{0, 2, 4, 5, 6},
+			Peers:      []string{"10.0.0.1:51413", "[::1]:6881"},
+		}, m)
+	})
+
+	t.Run("should normalize base32 hashes to hex", func(st *testing.T) {
+		m, err := ParseMagnet("magnet:?xt=urn:btih:" + strings.ToLower(base32Hash))
+		assert.NoError(st, err)
+		assert.Equal(st, hexHash, m.InfoHash)
+		assert.Equal(st, hexHash, m.HashString())
+	})
+
+	t.Run("should use the truncated v2 hash of v2 only links", func(st *testing.T) {
+		m, err := ParseMagnet("magnet:?xt=urn:btmh:1220" + v2Hash)
+		assert.NoError(st, err)
+		assert.Equal(st, "", m.InfoHash)
+		assert.Equal(st, v2Hash[:40], m.HashString())
+	})
+
+	t.Run("should reject malformed links", func(st *testing.T) {
+		tests := []struct {
+			name string
+			uri  string
+		}{
+			{name: "not a magnet", uri: "http://example.com/?xt=urn:btih:" + hexHash},
+			{name: "missing hash", uri: "magnet:?dn=debian"},
+			{name: "other networks only", uri: "magnet:?xt=urn:ed2k:abc"},
+			{name: "short hash", uri: "magnet:?xt=urn:btih:0123"},
+			{name: "invalid hex", uri: "magnet:?xt=urn:btih:" + strings.Repeat("z", 40)},
+			{name: "invalid base32", uri: "magnet:?xt=urn:btih:" + strings.Repeat("1", 32)},
+			{name: "unsupported multihash", uri: "magnet:?xt=urn:btmh:1320" + v2Hash},
+			{name: "conflicting hashes", uri: "magnet:?xt=urn:btih:" + hexHash + "&xt=urn:btih:" + strings.Repeat("0", 40)},
+			{name: "invalid escape", uri: "magnet:?xt=urn:btih:" + hexHash + "&dn=%zz"},
+			{name: "invalid length", uri: "magnet:?xt=urn:btih:" + hexHash + "&xl=-1"},
+			{name: "invalid selection", uri: "magnet:?xt=urn:btih:" + hexHash + "&so=3-1"},
+			{name: "huge selection", uri: "magnet:?xt=urn:btih:" + hexHash + "&so=0-99999999"},
+			{name: "invalid peer", uri: "magnet:?xt=urn:btih:" + hexHash + "&x.pe=10.0.0.1"},
+		}
+
+		for _, test := range tests {
+			// nolint
+			_, err := ParseMagnet(test.uri)
+			// nolint
+			assert.True(st, errors.Is(err, ErrInvalidMagnet), "%s: %v", test.name, err)
+		}
+	})
+}
+
+func TestMagnetString(t *testing.T) {
+	m := Magnet{
+		InfoHash:   strings.ToUpper(hexHash),
+		InfoHashV2: v2Hash,
+		Name:       "debian 11 & more.iso",
+		Length:     1024,
+		Trackers:   []string{"http://a/announce?k=1&p=2"},
+		WebSeeds:   []string{"http://mirror/"},
+		Select:     []int64{6, 0, 4, 5, 2},
+		Peers:      []string{"10.0.0.1:51413"},
+	}
+
+	t.Run("should build canonical links", func(st *testing.T) {
+		assert.Equal(st, "magnet:?xt=urn:btih:"+hexHash+"&xt=urn:btmh:1220"+v2Hash+
+			"&dn=debian%2011%20%26%20more.iso&xl=1024&tr=http%3A%2F%2Fa%2Fannounce%3Fk%3D1%26p%3D2"+
+			"&ws=http%3A%2F%2Fmirror%2F&so=0,2,4-6&x.pe=10.0.0.1%3A51413", m.String())
+	})
+
+	t.Run("should round trip", func(st *testing.T) {
+		parsed, err := ParseMagnet(m.String())
+		assert.NoError(st, err)
+		assert.Equal(st, parsed.String(), m.String())
+		assert.Equal(st, hexHash, parsed.InfoHash)
+		assert.Equal(st, m.Name, parsed.Name)
+		assert.Equal(st, m.Trackers, parsed.Trackers)
+		assert.Equal(st, []int64{0, 2, 4, 5, 6}, parsed.Select)
+	})
+
+	t.Run("should match torrents by hash", func(st *testing.T) {
+		assert.True(st, m.Matches(transmission.Torrent{HashString: hexHash}))
+		assert.False(st, m.Matches(transmission.Torrent{}))
+	})
+}
+
+func TestMetainfoMagnet(t *testing.T) {
+	data, _ := fileTree(t, true)
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	magnet, err := ParseMagnet(m.Magnet().String())
+	assert.NoError(t, err)
+	assert.Equal(t, m.HashString(), magnet.HashString())
+	assert.Equal(t, "album", magnet.Name)
+	assert.Equal(t, int64(35), magnet.Length)
+}
@@ -0,0 +1,400 @@
+// Package metainfo reads .torrent files, so they can be inspected before
+// being added to a daemon.
+package metainfo
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/bencode"
+)
+
+var (
+	// ErrInvalidMetainfo is the error of AddReader, so both can be matched
+	// with errors.Is.
+	ErrInvalidMetainfo = transmission.ErrInvalidMetainfo
+)
+
+// File is a file of the torrent, Path being relative to the torrent
+// directory (or the file name of single file torrents).
+type File struct {
+	Path   []string
+	Length int64
+	// Offset is the position of the file in the torrent data.
+	Offset int64
+}
+
+func (f File) String() string {
+	return strings.Join(f.Path, "/")
+}
+
+// Metainfo is the content of a .torrent file.
+type Metainfo struct {
+	Name         string
+	PieceLength  int64
+	Pieces       []byte // concatenated SHA-1 piece hashes, v1 only
+	Files        []File
+	Announce     string
+	AnnounceList [][]string
+	WebSeeds     []string
+	Private      bool
+	CreationDate time.Time
+	Comment      string
+	CreatedBy    string
+	Source       string
+
+	// InfoHash is the SHA-1 of the info dictionary, set for v1 and hybrid
+	// torrents. InfoHashV2 is its SHA-256, set for v2 and hybrid torrents.
+	InfoHash   [sha1.Size]byte
+	InfoHashV2 [sha256.Size]byte
+
+	// Info is the encoded info dictionary, as found in the file.
+	Info bencode.RawMessage
+
+	v1, v2 bool
+	// single is set for single file v1 torrents, whose data is not stored
+	// in a directory named after the torrent.
+	single bool
+}
+
+type rawMetainfo struct {
+	Announce     string             `bencode:"announce"`
+	AnnounceList [][]string         `bencode:"announce-list"`
+	Comment      string             `bencode:"comment"`
+	CreatedBy    string             `bencode:"created by"`
+	CreationDate int64              `bencode:"creation date"`
+	URLList      interface{}        `bencode:"url-list"`
+	Info         bencode.RawMessage `bencode:"info"`
+}
+
+type rawInfo struct {
+	Name        string                 `bencode:"name"`
+	PieceLength int64                  `bencode:"piece length"`
+	Pieces      []byte                 `bencode:"pieces"`
+	Length      int64                  `bencode:"length"`
+	Files       []rawFile              `bencode:"files"`
+	Private     int64                  `bencode:"private"`
+	Source      string                 `bencode:"source"`
+	MetaVersion int64                  `bencode:"meta version"`
+	FileTree    map[string]interface{} `bencode:"file tree"`
+}
+
+type rawFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+	Attr   string   `bencode:"attr"`
+}
+
+// Load parses the .torrent file at path.
+func Load(path string) (*Metainfo, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(buf)
+}
+
+// Parse parses the content of a .torrent file.
+func Parse(data []byte) (*Metainfo, error) {
+	var raw rawMetainfo
+	if err := bencode.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetainfo, err)
+	}
+
+	if len(raw.Info) == 0 {
+		return nil, fmt.Errorf("%w: missing info dictionary", ErrInvalidMetainfo)
+	}
+
+	var info rawInfo
+	if err := bencode.Unmarshal(raw.Info, &info); err != nil {
+		return nil, fmt.Errorf("%w: info: %v", ErrInvalidMetainfo, err)
+	}
+
+	m := &Metainfo{
+		Name:         info.Name,
+		PieceLength:  info.PieceLength,
+		Pieces:       info.Pieces,
+		Announce:     raw.Announce,
+		AnnounceList: raw.AnnounceList,
+		WebSeeds:     webSeeds(raw.URLList),
+		Private:      info.Private == 1,
+		Comment:      raw.Comment,
+		CreatedBy:    raw.CreatedBy,
+		Source:       info.Source,
+		Info:         raw.Info,
+		v1:           len(info.Pieces) > 0,
+		v2:           info.MetaVersion == 2,
+		single:       len(info.Pieces) > 0 && len(info.Files) == 0,
+	}
+
+	if raw.CreationDate > 0 {
+		m.CreationDate = time.Unix(raw.CreationDate, 0).UTC()
+	}
+
+	if err := m.validate(&info); err != nil {
+		return nil, err
+	}
+
+	if m.v1 {
+		m.InfoHash = sha1.Sum(raw.Info)
+		m.Files = v1Files(&info)
+	}
+
+	if m.v2 {
+		m.InfoHashV2 = sha256.Sum256(raw.Info)
+		if !m.v1 {
+			files, err := v2Files(info.FileTree, nil)
+			if err != nil {
+				return nil, err
+			}
+			m.Files = alignFiles(files, info.PieceLength)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Metainfo) validate(info *rawInfo) error {
+	switch {
+	case info.Name == "":
+		return fmt.Errorf("%w: missing name", ErrInvalidMetainfo)
+	case info.PieceLength <= 0:
+		return fmt.Errorf("%w: invalid piece length %d", ErrInvalidMetainfo, info.PieceLength)
+	case !m.v1 && !m.v2:
+		return fmt.Errorf("%w: missing pieces", ErrInvalidMetainfo)
+	case len(info.Pieces)%sha1.Size != 0:
+		return fmt.Errorf("%w: pieces length %d is not a multiple of %d", ErrInvalidMetainfo, len(info.Pieces), sha1.Size)
+	case m.v2 && info.FileTree == nil:
+		return fmt.Errorf("%w: missing file tree", ErrInvalidMetainfo)
+	}
+
+	if info.Length < 0 {
+		return fmt.Errorf("%w: negative length", ErrInvalidMetainfo)
+	}
+
+	if !validName(info.Name) {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidMetainfo, info.Name)
+	}
+
+	for _, f := range info.Files {
+		if f.Length < 0 || len(f.Path) == 0 {
+			return fmt.Errorf("%w: invalid file %q", ErrInvalidMetainfo, strings.Join(f.Path, "/"))
+		}
+
+		for _, name := range f.Path {
+			if !validName(name) {
+				return fmt.Errorf("%w: invalid file %q", ErrInvalidMetainfo, strings.Join(f.Path, "/"))
+			}
+		}
+	}
+
+	return nil
+}
+
+// validName reports whether name is a single path component, which can not
+// escape the download directory.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// v1Files lists the files of the info dictionary, without the padding files
+// of hybrid torrents, which the daemon does not list either. Their length
+// still counts in the offsets.
+func v1Files(info *rawInfo) []File {
+	if len(info.Files) == 0 {
+		return []File{{Path: []string{info.Name}, Length: info.Length}}
+	}
+
+	files := make([]File, 0, len(info.Files))
+
+	var offset int64
+	for _, f := range info.Files {
+		if !strings.Contains(f.Attr, "p") {
+			files = append(files, File{Path: f.Path, Length: f.Length, Offset: offset})
+		}
+		offset += f.Length
+	}
+
+	return files
+}
+
+// alignFiles sets the offsets of v2 files, which all start on a piece
+// boundary.
+func alignFiles(files []File, pieceLength int64) []File {
+	var offset int64
+	for i := range files {
+		if rem := offset % pieceLength; rem != 0 {
+			offset += pieceLength - rem
+		}
+		files[i].Offset = offset
+		offset += files[i].Length
+	}
+
+	return files
+}
+
+// v2Files walks a v2 file tree, in which files are dictionaries holding an
+// empty key.
+func v2Files(tree map[string]interface{}, path []string) ([]File, error) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []File
+
+	for _, name := range names {
+		node, ok := tree[name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid file tree entry %q", ErrInvalidMetainfo, name)
+		}
+
+		if name == "" {
+			length, _ := node["length"].(int64)
+			if length < 0 || len(path) == 0 {
+				return nil, fmt.Errorf("%w: invalid file tree entry %q", ErrInvalidMetainfo, strings.Join(path, "/"))
+			}
+			files = append(files, File{Path: append([]string(nil), path...), Length: length})
+			continue
+		}
+
+		if !validName(name) {
+			return nil, fmt.Errorf("%w: invalid file tree entry %q", ErrInvalidMetainfo, name)
+		}
+
+		children, err := v2Files(node, append(path, name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, children...)
+	}
+
+	return files, nil
+}
+
+func webSeeds(urlList interface{}) []string {
+	switch v := urlList.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []interface{}:
+		var seeds []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				seeds = append(seeds, s)
+			}
+		}
+		return seeds
+	}
+
+	return nil
+}
+
+// IsV1 reports whether the torrent has v1 piece hashes (v1 or hybrid).
+func (m *Metainfo) IsV1() bool {
+	return m.v1
+}
+
+// IsV2 reports whether the torrent has a v2 file tree (v2 or hybrid).
+func (m *Metainfo) IsV2() bool {
+	return m.v2
+}
+
+// IsHybrid reports whether the torrent has both v1 and v2 metadata.
+func (m *Metainfo) IsHybrid() bool {
+	return m.v1 && m.v2
+}
+
+// HashString returns the infohash as the daemon reports it in
+// Torrent.HashString: the hex v1 hash, or the truncated v2 hash of v2 only
+// torrents.
+func (m *Metainfo) HashString() string {
+	if m.v1 {
+		return hex.EncodeToString(m.InfoHash[:])
+	}
+
+	return hex.EncodeToString(m.InfoHashV2[:sha1.Size])
+}
+
+// TotalSize returns the size of the files, padding excluded.
+func (m *Metainfo) TotalSize() int64 {
+	var size int64
+	for _, f := range m.Files {
+		size += f.Length
+	}
+
+	return size
+}
+
+// PieceCount returns the number of v1 pieces.
+func (m *Metainfo) PieceCount() int {
+	return len(m.Pieces) / sha1.Size
+}
+
+// Trackers returns the announce urls, from the announce list when present,
+// without duplicates.
+func (m *Metainfo) Trackers() []string {
+	var trackers []string
+	seen := make(map[string]bool)
+
+	add := func(tracker string) {
+		if tracker != "" && !seen[tracker] {
+			seen[tracker] = true
+			trackers = append(trackers, tracker)
+		}
+	}
+
+	for _, tier := range m.AnnounceList {
+		for _, tracker := range tier {
+			add(tracker)
+		}
+	}
+
+	if len(trackers) == 0 {
+		add(m.Announce)
+	}
+
+	return trackers
+}
+
+// Matches reports whether t is this torrent, comparing their hashes.
+func (m *Metainfo) Matches(t transmission.Torrent) bool {
+	return t.HashString != "" && strings.EqualFold(t.HashString, m.HashString())
+}
+
+// Find returns the torrent of torrents matching m, e.g. to detect duplicates
+// before calling TorrentAdd. The torrents must have been fetched with the
+// "hashString" field.
+func (m *Metainfo) Find(torrents []transmission.Torrent) (transmission.Torrent, bool) {
+	for _, t := range torrents {
+		if m.Matches(t) {
+			return t, true
+		}
+	}
+
+	return transmission.Torrent{}, false
+}
+
+// FileIndices splits the file indices between the ones wanted returns true
+// for and the others, as expected by FilesWanted and FilesUnwanted.
+func (m *Metainfo) FileIndices(wanted func(File) bool) (want []int64, unwant []int64) {
+	for i, f := range m.Files {
+		if wanted(f) {
+			want = append(want, int64(i))
+		} else {
+			unwant = append(unwant, int64(i))
+		}
+	}
+
+	return want, unwant
+}
@@ -0,0 +1,260 @@
+package metainfo
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/bencode"
+	"github.com/stretchr/testify/assert"
+)
+
+func encode(t *testing.T, v interface{}) []byte {
+	buf, err := bencode.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf
+}
+
+func singleFile(t *testing.T) ([]byte, []byte) {
+	info := encode(t, map[string]interface{}{
+		"name":         "debian.iso",
+		"length":       40000,
+		"piece length": 16384,
+		"pieces":       strings.Repeat("a", 3*sha1.Size),
+		"private":      1,
+		"source":       "TRK",
+	})
+
+	return encode(t, map[string]interface{}{
+		"announce":      "http://a/announce",
+		"announce-list": [][]string{{"http://a/announce", "http://b/announce"}, {"udp://c:80"}},
+		"comment":       "debian",
+		"created by":    "mktorrent",
+		"creation date": 1600000000,
+		"url-list":      "http://mirror/debian.iso",
+		"info":          bencode.RawMessage(info),
+	}), info
+}
+
+func multiFile(t *testing.T) []byte {
+	return encode(t, map[string]interface{}{
+		"announce": "http://a/announce",
+		"url-list": []string{"http://m1/", "http://m2/"},
+		"info": map[string]interface{}{
+			"name":         "album",
+			"piece length": 16384,
+			"pieces":       strings.Repeat("b", sha1.Size),
+			"files": []interface{}{
+				map[string]interface{}{"length": 10, "path": []string{"cd1", "01.flac"}},
+				map[string]interface{}{"length": 16374, "path": []string{".pad", "16374"}, "attr": "p"},
+				map[string]interface{}{"length": 20, "path": []string{"cd1", "02.flac"}},
+				map[string]interface{}{"length": 5, "path": []string{"cover.jpg"}},
+			},
+		},
+	})
+}
+
+func fileTree(t *testing.T, hybrid bool) ([]byte, []byte) {
+	info := map[string]interface{}{
+		"name":         "album",
+		"piece length": 16384,
+		"meta version": 2,
+		"file tree": map[string]interface{}{
+			"cover.jpg": map[string]interface{}{"": map[string]interface{}{"length": 5}},
+			"cd1": map[string]interface{}{
+				"02.flac": map[string]interface{}{"": map[string]interface{}{"length": 20}},
+				"01.flac": map[string]interface{}{"": map[string]interface{}{"length": 10}},
+			},
+		},
+	}
+
+	if hybrid {
+		info["pieces"] = strings.Repeat("c", 3*sha1.Size)
+		info["files"] = []interface{}{
+			map[string]interface{}{"length": 10, "path": []string{"cd1", "01.flac"}},
+			map[string]interface{}{"length": 16374, "path": []string{".pad", "16374"}, "attr": "p"},
+			map[string]interface{}{"length": 20, "path": []string{"cd1", "02.flac"}},
+			map[string]interface{}{"length": 16364, "path": []string{".pad", "16364"}, "attr": "p"},
+			map[string]interface{}{"length": 5, "path": []string{"cover.jpg"}},
+		}
+	}
+
+	raw := encode(t, info)
+
+	return encode(t, map[string]interface{}{"info": bencode.RawMessage(raw)}), raw
+}
+
+func TestParse(t *testing.T) {
+	t.Run("should parse single file torrents", func(st *testing.T) {
+		data, info := singleFile(st)
+
+		m, err := Parse(data)
+		assert.NoError(st, err)
+		assert.Equal(st, "debian.iso", m.Name)
+		assert.Equal(st, int64(16384), m.PieceLength)
+		assert.Equal(st, 3, m.PieceCount())
+		assert.Equal(st, []File{{Path: []string{"debian.iso"}, Length: 40000}}, m.Files)
+		assert.Equal(st, int64(40000), m.TotalSize())
+		assert.True(st, m.Private)
+		assert.Equal(st, "TRK", m.Source)
+		assert.Equal(st, "debian", m.Comment)
+		assert.Equal(st, "mktorrent", m.CreatedBy)
+		assert.Equal(st, time.Unix(1600000000, 0).UTC(), m.CreationDate)
+		assert.Equal(st, []string{"http://mirror/debian.iso"}, m.WebSeeds)
+		assert.Equal(st, []string{"http://a/announce", "http://b/announce", "udp://c:80"}, m.Trackers())
+		assert.Equal(st, info, []byte(m.Info))
+
+		sum := sha1.Sum(info)
+		assert.Equal(st, hex.EncodeToString(sum[:]), m.HashString())
+		assert.True(st, m.IsV1())
+		assert.False(st, m.IsV2())
+	})
+
+	t.Run("should parse multi file torrents without padding files", func(st *testing.T) {
+		m, err := Parse(multiFile(st))
+		assert.NoError(st, err)
+		assert.Equal(st, []File{
+			{Path: []string{"cd1", "01.flac"}, Length: 10},
+			{Path: []string{"cd1", "02.flac"}, Length: 20, Offset: 16384},
+			{Path: []string{"cover.jpg"}, Length: 5, Offset: 16404},
+		}, m.Files)
+		assert.Equal(st, int64(35), m.TotalSize())
+		assert.Equal(st, "cd1/02.flac", m.Files[1].String())
+		assert.Equal(st, []string{"http://m1/", "http://m2/"}, m.WebSeeds)
+		assert.Equal(st, []string{"http://a/announce"}, m.Trackers())
+		assert.False(st, m.Private)
+		assert.True(st, m.CreationDate.IsZero())
+	})
+
+	t.Run("should parse hybrid torrents", func(st *testing.T) {
+		data, info := fileTree(st, true)
+
+		m, err := Parse(data)
+		assert.NoError(st, err)
+		assert.True(st, m.IsHybrid())
+		assert.Len(st, m.Files, 3)
+
+		sum1, sum2 := sha1.Sum(info), sha256.Sum256(info)
+		assert.Equal(st, sum1, m.InfoHash)
+		assert.Equal(st, sum2, m.InfoHashV2)
+		assert.Equal(st, hex.EncodeToString(sum1[:]), m.HashString())
+	})
+
+	t.Run("should parse v2 only torrents from the file tree", func(st *testing.T) {
+		data, info := fileTree(st, false)
+
+		m, err := Parse(data)
+		assert.NoError(st, err)
+		assert.True(st, m.IsV2())
+		assert.False(st, m.IsV1())
+		assert.Equal(st, []File{
+			{Path: []string{"cd1", "01.flac"}, Length: 10},
+			{Path: []string{"cd1", "02.flac"}, Length: 20, Offset: 16384},
+			{Path: []string{"cover.jpg"}, Length: 5, Offset: 32768},
+		}, m.Files)
+
+		sum := sha256.Sum256(info)
+		assert.Equal(st, [sha1.Size]byte{}, m.InfoHash)
+		assert.Equal(st, hex.EncodeToString(sum[:sha1.Size]), m.HashString())
+	})
+
+	t.Run("should reject invalid metainfo", func(st *testing.T) {
+		tests := []struct {
+			name string
+			data string
+		}{
+			{name: "not bencode", data: "<html>"},
+			{name: "not a dictionary", data: "4:spam"},
+			{name: "missing info", data: "d8:announce1:ae"},
+			{name: "missing name", data: "d4:infod12:piece lengthi1e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"},
+			{name: "missing pieces", data: "d4:infod4:name1:a12:piece lengthi1eee"},
+			{name: "truncated pieces", data: "d4:infod4:name1:a12:piece lengthi1e6:pieces3:abcee"},
+			{name: "zero piece length", data: "d4:infod4:name1:a12:piece lengthi0e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"},
+			{name: "file without path", data: "d4:infod5:filesld6:lengthi1eee4:name1:a12:piece lengthi1e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"},
+			{name: "escaping path", data: "d4:infod5:filesld6:lengthi1e4:pathl2:..1:aeee4:name1:a12:piece lengthi1e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"},
+			{name: "absolute path", data: "d4:infod5:filesld6:lengthi1e4:pathl4:/etc6:passwdeee4:name1:a12:piece lengthi1e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"},
+			{name: "escaping name", data: "d4:infod6:lengthi1e4:name2:..12:piece lengthi1e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"},
+			{name: "name with separator", data: "d4:infod6:lengthi1e4:name3:a/b12:piece lengthi1e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"},
+			{name: "escaping file tree", data: "d4:infod9:file treed2:..d1:ad0:d6:lengthi1eeeee12:meta versioni2e4:name1:a12:piece lengthi1eee"},
+			{name: "v2 without file tree", data: "d4:infod12:meta versioni2e4:name1:a12:piece lengthi1eee"},
+			{name: "invalid file tree", data: "d4:infod9:file treed1:ai1ee12:meta versioni2e4:name1:a12:piece lengthi1eee"},
+		}
+
+		for _, test := range tests {
+			// nolint
+			_, err := Parse([]byte(test.data))
+			// nolint
+			assert.True(st, errors.Is(err, ErrInvalidMetainfo), "%s: %v", test.name, err)
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metainfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "album.torrent")
+	if err := ioutil.WriteFile(path, multiFile(t), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should parse the file", func(st *testing.T) {
+		m, err := Load(path)
+		assert.NoError(st, err)
+		assert.Equal(st, "album", m.Name)
+	})
+
+	t.Run("should return read errors", func(st *testing.T) {
+		_, err := Load(filepath.Join(dir, "missing.torrent"))
+		assert.True(st, os.IsNotExist(err))
+	})
+}
+
+func TestMetainfoFind(t *testing.T) {
+	data, _ := singleFile(t)
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	torrents := []transmission.Torrent{
+		{ID: 1, HashString: strings.Repeat("0", 40)},
+		{ID: 2, HashString: strings.ToUpper(m.HashString())},
+	}
+
+	t.Run("should find the torrent with the same hash", func(st *testing.T) {
+		torrent, ok := m.Find(torrents)
+		assert.True(st, ok)
+		assert.Equal(st, int64(2), torrent.ID)
+	})
+
+	t.Run("should not match torrents without hash", func(st *testing.T) {
+		_, ok := m.Find([]transmission.Torrent{{ID: 3}})
+		assert.False(st, ok)
+	})
+}
+
+func TestMetainfoFileIndices(t *testing.T) {
+	m, err := Parse(multiFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, unwant := m.FileIndices(func(f File) bool { return strings.HasSuffix(f.String(), ".flac") })
+	assert.Equal(t, []int64{0, 1}, want)
+	assert.Equal(t, []int64{2}, unwant)
+}
@@ -0,0 +1,177 @@
+package metainfo
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// storage reads the torrent data from the files stored under dir. The gaps
+// between files, left by padding files, read as zeros.
+type storage struct {
+	dir         string
+	files       []File
+	pieceLength int64
+	size        int64
+	buffers     sync.Pool
+}
+
+func newStorage(dir string, files []File, pieceLength int64) *storage {
+	s := storage{dir: dir, files: files, pieceLength: pieceLength}
+
+	if n := len(files); n > 0 {
+		s.size = files[n-1].Offset + files[n-1].Length
+	}
+
+	s.buffers.New = func() interface{} { return make([]byte, pieceLength) }
+
+	return &s
+}
+
+func (s *storage) pieceCount() int {
+	return int((s.size + s.pieceLength - 1) / s.pieceLength)
+}
+
+// pieceFiles returns the indices of the files overlapping the piece.
+func (s *storage) pieceFiles(index int) []int {
+	start := int64(index) * s.pieceLength
+	end := start + s.pieceLength
+
+	first := sort.Search(len(s.files), func(i int) bool {
+		return s.files[i].Offset+s.files[i].Length > start
+	})
+
+	var indices []int
+	for i := first; i < len(s.files) && s.files[i].Offset < end; i++ {
+		if s.files[i].Length > 0 {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices
+}
+
+// hashPiece returns the SHA-1 of the piece, or the *fileError of the first
+// file which could not be read.
+func (s *storage) hashPiece(index int) ([sha1.Size]byte, error) {
+	start := int64(index) * s.pieceLength
+	length := s.pieceLength
+	if start+length > s.size {
+		length = s.size - start
+	}
+
+	buf := s.buffers.Get().([]byte)
+	defer s.buffers.Put(buf) // nolint: staticcheck
+
+	piece := buf[:length]
+	for i := range piece {
+		piece[i] = 0
+	}
+
+	for _, i := range s.pieceFiles(index) {
+		if err := s.read(s.files[i], piece, start); err != nil {
+			return [sha1.Size]byte{}, &fileError{index: i, err: err}
+		}
+	}
+
+	return sha1.Sum(piece), nil
+}
+
+// read copies the part of f overlapping piece, which starts at offset start.
+func (s *storage) read(f File, piece []byte, start int64) error {
+	from, to := f.Offset, f.Offset+f.Length
+	if from < start {
+		from = start
+	}
+	if end := start + int64(len(piece)); to > end {
+		to = end
+	}
+
+	path := filepath.Join(append([]string{s.dir}, f.Path...)...)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.ReadAt(piece[from-start:to-start], from-f.Offset); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("%s: %w", path, io.ErrUnexpectedEOF)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// fileError is a read error of the file at index.
+type fileError struct {
+	index int
+	err   error
+}
+
+func (e *fileError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fileError) Unwrap() error {
+	return e.err
+}
+
+// eachPiece calls fn for every piece index from workers goroutines, stopping
+// at the first error.
+func eachPiece(ctx context.Context, count, workers int, fn func(index int) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indices := make(chan int)
+	errs := make(chan error, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				if err := fn(index); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	var err error
+
+loop:
+	for index := 0; index < count; index++ {
+		select {
+		case indices <- index:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	close(indices)
+	wg.Wait()
+
+	select {
+	case err = <-errs:
+	default:
+		err = ctx.Err()
+	}
+
+	return err
+}
@@ -0,0 +1,156 @@
+package metainfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	ErrNoPieceHashes = errors.New("no v1 piece hashes to verify")
+	ErrSizeMismatch  = errors.New("file size mismatch")
+)
+
+// VerifyOptions configures Verify.
+type VerifyOptions struct {
+	// Workers hashing the pieces, runtime.NumCPU() when 0.
+	Workers int
+	// Progress is called after each checked piece.
+	Progress func(done, total int)
+}
+
+// VerifyResult is the state of the data of a torrent. Pieces tells for every
+// piece whether it matches its hash.
+type VerifyResult struct {
+	Pieces []bool
+	Files  []FileResult
+}
+
+// FileResult is the state of a file. Pieces is the number of pieces the file
+// overlaps and Valid the number of them matching their hash. Err is set when
+// the file is missing, has the wrong size or could not be read.
+type FileResult struct {
+	File
+	Pieces int
+	Valid  int
+	Err    error
+}
+
+// Complete reports whether the file exists and all its pieces are valid.
+func (r FileResult) Complete() bool {
+	return r.Err == nil && r.Valid == r.Pieces
+}
+
+// Complete reports whether all the pieces are valid.
+func (r VerifyResult) Complete() bool {
+	for _, valid := range r.Pieces {
+		if !valid {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PercentDone returns the fraction of valid pieces, as Torrent.PercentDone.
+func (r VerifyResult) PercentDone() float64 {
+	if len(r.Pieces) == 0 {
+		return 0
+	}
+
+	valid := 0
+	for _, ok := range r.Pieces {
+		if ok {
+			valid++
+		}
+	}
+
+	return float64(valid) / float64(len(r.Pieces))
+}
+
+// Verify checks the data stored in downloadDir against the piece hashes of m,
+// as the daemon would after TorrentAdd with the same download dir. Missing or
+// unreadable files are reported in the results, the error is only set for
+// invalid metainfo or when ctx is done.
+func Verify(ctx context.Context, m *Metainfo, downloadDir string, opts VerifyOptions) (VerifyResult, error) {
+	if !m.v1 {
+		return VerifyResult{}, fmt.Errorf("%w: %s", ErrNoPieceHashes, m.Name)
+	}
+
+	dir := downloadDir
+	if !m.single {
+		dir = filepath.Join(downloadDir, m.Name)
+	}
+
+	s := newStorage(dir, m.Files, m.PieceLength)
+	count := s.pieceCount()
+	if count != m.PieceCount() {
+		return VerifyResult{}, fmt.Errorf("%w: %d pieces for %d bytes", ErrInvalidMetainfo, m.PieceCount(), s.size)
+	}
+
+	result := VerifyResult{
+		Pieces: make([]bool, count),
+		Files:  make([]FileResult, len(m.Files)),
+	}
+
+	for i, f := range m.Files {
+		result.Files[i] = FileResult{File: f, Err: checkFile(dir, f)}
+	}
+
+	var mu sync.Mutex
+	done := 0
+
+	err := eachPiece(ctx, count, opts.Workers, func(index int) error {
+		hash, err := s.hashPiece(index)
+		valid := err == nil && string(hash[:]) == string(m.Pieces[index*len(hash):(index+1)*len(hash)])
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		result.Pieces[index] = valid
+
+		var fileErr *fileError
+		if errors.As(err, &fileErr) && result.Files[fileErr.index].Err == nil {
+			result.Files[fileErr.index].Err = fileErr.err
+		}
+
+		done++
+		if opts.Progress != nil {
+			opts.Progress(done, count)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return VerifyResult{}, err
+	}
+
+	for index, valid := range result.Pieces {
+		for _, i := range s.pieceFiles(index) {
+			result.Files[i].Pieces++
+			if valid {
+				result.Files[i].Valid++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// checkFile returns the error of a missing file or of a file of the wrong
+// size.
+func checkFile(dir string, f File) error {
+	stat, err := os.Stat(filepath.Join(append([]string{dir}, f.Path...)...))
+	if err != nil {
+		return err
+	}
+
+	if stat.Size() != f.Length {
+		return fmt.Errorf("%w: %s is %d bytes, expected %d", ErrSizeMismatch, f, stat.Size(), f.Length)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package metainfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "metainfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	setup := func(t *testing.T) *Metainfo {
+		root := filepath.Join(dir, "dataset")
+		if err := os.RemoveAll(root); err != nil {
+			t.Fatal(err)
+		}
+
+		writeFiles(t, root, map[string]int{"a.bin": 40000, "b.bin": 10, "c.bin": 30000})
+
+		buf, err := Create(ctx, root, CreateOptions{PieceLength: 16 << 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := Parse(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return m
+	}
+
+	t.Run("should report complete data", func(st *testing.T) {
+		m := setup(st)
+		progress := 0
+
+		result, err := Verify(ctx, m, dir, VerifyOptions{Workers: 2, Progress: func(done, total int) { progress = done }})
+		assert.NoError(st, err)
+		assert.True(st, result.Complete())
+		assert.Equal(st, 1.0, result.PercentDone())
+		assert.Equal(st, 5, progress)
+		assert.Equal(st, []FileResult{
+			{File: m.Files[0], Pieces: 3, Valid: 3},
+			{File: m.Files[1], Pieces: 1, Valid: 1},
+			{File: m.Files[2], Pieces: 3, Valid: 3},
+		}, result.Files)
+	})
+
+	t.Run("should report corrupted pieces", func(st *testing.T) {
+		m := setup(st)
+
+		path := filepath.Join(dir, "dataset", "c.bin")
+		data, _ := ioutil.ReadFile(path)
+		data[len(data)-1] ^= 0xff
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			st.Fatal(err)
+		}
+
+		result, err := Verify(ctx, m, dir, VerifyOptions{})
+		assert.NoError(st, err)
+		assert.False(st, result.Complete())
+		assert.Equal(st, []bool{true, true, true, true, false}, result.Pieces)
+		assert.Equal(st, 0.8, result.PercentDone())
+		assert.True(st, result.Files[0].Complete())
+		assert.True(st, result.Files[1].Complete())
+		assert.False(st, result.Files[2].Complete())
+		assert.Equal(st, 2, result.Files[2].Valid)
+		assert.NoError(st, result.Files[2].Err)
+	})
+
+	t.Run("should report missing and truncated files", func(st *testing.T) {
+		m := setup(st)
+
+		if err := os.Remove(filepath.Join(dir, "dataset", "b.bin")); err != nil {
+			st.Fatal(err)
+		}
+		if err := os.Truncate(filepath.Join(dir, "dataset", "c.bin"), 100); err != nil {
+			st.Fatal(err)
+		}
+
+		result, err := Verify(ctx, m, dir, VerifyOptions{})
+		assert.NoError(st, err)
+		assert.Equal(st, []bool{true, true, false, false, false}, result.Pieces)
+		assert.Equal(st, 2, result.Files[0].Valid)
+		assert.True(st, os.IsNotExist(result.Files[1].Err))
+		assert.True(st, errors.Is(result.Files[2].Err, ErrSizeMismatch))
+	})
+
+	t.Run("should verify single file torrents in the download dir", func(st *testing.T) {
+		setup(st)
+
+		buf, err := Create(ctx, filepath.Join(dir, "dataset", "a.bin"), CreateOptions{})
+		assert.NoError(st, err)
+		m, err := Parse(buf)
+		assert.NoError(st, err)
+
+		result, err := Verify(ctx, m, filepath.Join(dir, "dataset"), VerifyOptions{})
+		assert.NoError(st, err)
+		assert.True(st, result.Complete())
+	})
+
+	t.Run("should read padding as zeros", func(st *testing.T) {
+		root := filepath.Join(dir, "album")
+		data := writeFiles(st, root, map[string]int{"cd1/01.flac": 10, "cd1/02.flac": 20, "cover.jpg": 5})
+
+		content := append(append([]byte(nil), data[:10]...), make([]byte, 16374)...)
+		content = append(append(content, data[10:30]...), make([]byte, 16364)...)
+		content = append(content, data[30:]...)
+
+		torrent, _ := fileTree(st, true)
+		m, err := Parse(torrent)
+		assert.NoError(st, err)
+		m.Pieces = pieceHashes(content, 16384)
+
+		result, err := Verify(ctx, m, dir, VerifyOptions{})
+		assert.NoError(st, err)
+		assert.True(st, result.Complete())
+		assert.Equal(st, 1, result.Files[2].Pieces)
+	})
+
+	t.Run("should reject v2 only torrents", func(st *testing.T) {
+		torrent, _ := fileTree(st, false)
+		m, err := Parse(torrent)
+		assert.NoError(st, err)
+
+		_, err = Verify(ctx, m, dir, VerifyOptions{})
+		assert.True(st, errors.Is(err, ErrNoPieceHashes))
+	})
+
+	t.Run("should reject pieces not matching the files", func(st *testing.T) {
+		m := setup(st)
+		m.Pieces = bytes.Repeat([]byte{0}, 20)
+
+		_, err := Verify(ctx, m, dir, VerifyOptions{})
+		assert.True(st, errors.Is(err, ErrInvalidMetainfo))
+	})
+
+	t.Run("should stop when the context is done", func(st *testing.T) {
+		m := setup(st)
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := Verify(ctx, m, dir, VerifyOptions{})
+		assert.Equal(st, context.Canceled, err)
+	})
+}
@@ -0,0 +1,34 @@
+package transmission
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DefaultPollInterval = time.Second
+)
+
+// poll calls fn right away and then every interval, until it returns true,
+// an error, or ctx is done.
+func poll(ctx context.Context, interval time.Duration, fn func(ctx context.Context) (bool, error)) error {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		done, err := fn(ctx)
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package transmission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultPoolConcurrency = 4
+)
+
+var (
+	ErrTorrentNotFound = errors.New("torrent not found")
+	ErrDaemonNotFound  = errors.New("daemon not found in pool")
+)
+
+// Pool groups the clients of several daemons, identified by name, and runs
+// the same operation on all of them concurrently.
+type Pool struct {
+	clients     map[string]*Client
+	names       []string
+	concurrency int
+
+	mu     sync.RWMutex
+	owners map[string]string // torrent hash -> daemon name
+}
+
+type PoolOption func(*Pool)
+
+// WithConcurrency limits how many daemons are queried at the same time.
+func WithConcurrency(concurrency int) PoolOption {
+	return func(p *Pool) {
+		if concurrency <= 0 {
+			concurrency = DefaultPoolConcurrency
+		}
+		p.concurrency = concurrency
+	}
+}
+
+func NewPool(clients map[string]*Client, opts ...PoolOption) *Pool {
+	pool := Pool{
+		clients:     make(map[string]*Client, len(clients)),
+		concurrency: DefaultPoolConcurrency,
+		owners:      make(map[string]string),
+	}
+
+	for name, client := range clients {
+		pool.clients[name] = client
+		pool.names = append(pool.names, name)
+	}
+
+	sort.Strings(pool.names)
+
+	for _, o := range opts {
+		o(&pool)
+	}
+
+	return &pool
+}
+
+// Names returns the daemon names, sorted. Results of fan-out operations
+// follow the same order.
+func (p *Pool) Names() []string {
+	return append([]string(nil), p.names...)
+}
+
+func (p *Pool) Client(name string) (*Client, bool) {
+	client, ok := p.clients[name]
+	return client, ok
+}
+
+// each calls fn for every daemon, with at most p.concurrency calls running
+// at the same time. fn receives the index of the daemon in p.names.
+func (p *Pool) each(ctx context.Context, fn func(ctx context.Context, i int, client *Client)) {
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, p.concurrency)
+
+	for i, name := range p.names {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			// the remaining calls fail right away with the context error
+			fn(ctx, i, p.clients[name])
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fn(ctx, i, client)
+		}(i, p.clients[name])
+	}
+
+	wg.Wait()
+}
+
+type DaemonTorrents struct {
+	Daemon   string
+	Torrents []Torrent
+	Err      error
+}
+
+type DaemonSession struct {
+	Daemon  string
+	Session Session
+	Err     error
+}
+
+type DaemonStats struct {
+	Daemon string
+	Stats  SessionStats
+	Err    error
+}
+
+func (p *Pool) TorrentGet(ctx context.Context, args TorrentGet) []DaemonTorrents {
+	results := make([]DaemonTorrents, len(p.names))
+
+	p.each(ctx, func(ctx context.Context, i int, client *Client) {
+		torrents, err := client.TorrentGet(ctx, args)
+		results[i] = DaemonTorrents{Daemon: p.names[i], Torrents: torrents, Err: err}
+	})
+
+	p.mu.Lock()
+	for _, result := range results {
+		for _, torrent := range result.Torrents {
+			if torrent.HashString != "" {
+				p.owners[strings.ToLower(torrent.HashString)] = result.Daemon
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	return results
+}
+
+func (p *Pool) SessionGet(ctx context.Context) []DaemonSession {
+	results := make([]DaemonSession, len(p.names))
+
+	p.each(ctx, func(ctx context.Context, i int, client *Client) {
+		session, err := client.SessionGet(ctx)
+		results[i] = DaemonSession{Daemon: p.names[i], Session: session, Err: err}
+	})
+
+	return results
+}
+
+func (p *Pool) SessionStats(ctx context.Context) []DaemonStats {
+	results := make([]DaemonStats, len(p.names))
+
+	p.each(ctx, func(ctx context.Context, i int, client *Client) {
+		stats, err := client.SessionStats(ctx)
+		results[i] = DaemonStats{Daemon: p.names[i], Stats: stats, Err: err}
+	})
+
+	return results
+}
+
+// PoolStats sums the session stats of the daemons that answered.
+type PoolStats struct {
+	Daemons            int
+	Failed             int
+	ActiveTorrentCount int64
+	PausedTorrentCount int64
+	TorrentCount       int64
+	DownloadSpeed      int64
+	UploadSpeed        int64
+	CumulativeStats    CumulativeStats
+	CurrentStats       CurrentStats
+}
+
+func AggregateStats(results []DaemonStats) PoolStats {
+	var total PoolStats
+
+	for _, result := range results {
+		total.Daemons++
+		if result.Err != nil {
+			total.Failed++
+			continue
+		}
+
+		s := result.Stats
+		total.ActiveTorrentCount += s.ActiveTorrentCount
+		total.PausedTorrentCount += s.PausedTorrentCount
+		total.TorrentCount += s.TorrentCount
+		total.DownloadSpeed += s.DownloadSpeed
+		total.UploadSpeed += s.UploadSpeed
+
+		total.CumulativeStats.UploadedBytes += s.CumulativeStats.UploadedBytes
+		total.CumulativeStats.DownloadedBytes += s.CumulativeStats.DownloadedBytes
+		total.CumulativeStats.FilesAdded += s.CumulativeStats.FilesAdded
+		total.CumulativeStats.SessionCount += s.CumulativeStats.SessionCount
+		total.CumulativeStats.SecondsActive += s.CumulativeStats.SecondsActive
+
+		total.CurrentStats.UploadedBytes += s.CurrentStats.UploadedBytes
+		total.CurrentStats.DownloadedBytes += s.CurrentStats.DownloadedBytes
+		total.CurrentStats.FilesAdded += s.CurrentStats.FilesAdded
+		total.CurrentStats.SessionCount += s.CurrentStats.SessionCount
+		total.CurrentStats.SecondsActive += s.CurrentStats.SecondsActive
+	}
+
+	return total
+}
+
+// TorrentTotals sums the torrents of the daemons that answered. Only the
+// fields requested in TorrentGet are meaningful.
+type TorrentTotals struct {
+	Daemons        int
+	Failed         int
+	Count          int64
+	RateDownload   int64
+	RateUpload     int64
+	TotalSize      int64
+	SizeWhenDone   int64
+	LeftUntilDone  int64
+	DownloadedEver int64
+	UploadedEver   int64
+}
+
+func AggregateTorrents(results []DaemonTorrents) TorrentTotals {
+	var total TorrentTotals
+
+	for _, result := range results {
+		total.Daemons++
+		if result.Err != nil {
+			total.Failed++
+			continue
+		}
+
+		for _, t := range result.Torrents {
+			total.Count++
+			total.RateDownload += t.RateDownload
+			total.RateUpload += t.RateUpload
+			total.TotalSize += t.TotalSize
+			total.SizeWhenDone += t.SizeWhenDone
+			total.LeftUntilDone += t.LeftUntilDone
+			total.DownloadedEver += t.DownloadedEver
+			total.UploadedEver += t.UploadedEver
+		}
+	}
+
+	return total
+}
+
+// Locate returns the name of the daemon owning the torrent with the given
+// hash. The owner seen in previous TorrentGet calls is asked first, then
+// every daemon when the torrent is no longer there.
+func (p *Pool) Locate(ctx context.Context, hash string) (string, error) {
+	hash = strings.ToLower(hash)
+	args := TorrentGet{Ids: []string{hash}, Fields: []string{"id", "hashString"}}
+
+	p.mu.RLock()
+	name, ok := p.owners[hash]
+	p.mu.RUnlock()
+
+	if client, found := p.clients[name]; ok && found {
+		torrents, err := client.TorrentGet(ctx, args)
+		if err == nil && hasHash(torrents, hash) {
+			return name, nil
+		}
+
+		p.Forget(hash)
+	}
+
+	results := p.TorrentGet(ctx, args)
+
+	var errs []string
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", result.Daemon, result.Err))
+			continue
+		}
+
+		if hasHash(result.Torrents, hash) {
+			return result.Daemon, nil
+		}
+	}
+
+	if len(errs) > 0 {
+		return "", fmt.Errorf("%w: %s (%s)", ErrTorrentNotFound, hash, strings.Join(errs, "; "))
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrTorrentNotFound, hash)
+}
+
+func hasHash(torrents []Torrent, hash string) bool {
+	for _, torrent := range torrents {
+		if strings.EqualFold(torrent.HashString, hash) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Route runs fn with the client of the daemon owning the given torrent hash,
+// e.g. to start, stop or remove it.
+func (p *Pool) Route(ctx context.Context, hash string, fn func(ctx context.Context, client *Client) error) error {
+	name, err := p.Locate(ctx, hash)
+	if err != nil {
+		return err
+	}
+
+	client, ok := p.clients[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrDaemonNotFound, name)
+	}
+
+	return fn(ctx, client)
+}
+
+// Forget removes the cached owner of a torrent, e.g. after removing it.
+func (p *Pool) Forget(hash string) {
+	p.mu.Lock()
+	delete(p.owners, strings.ToLower(hash))
+	p.mu.Unlock()
+}
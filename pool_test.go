@@ -0,0 +1,198 @@
+package transmission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newPoolDaemon answers torrent-get, session-get and session-stats with the
+// given torrents and speed, tracking how many requests run at the same time.
+func newPoolDaemon(hashes []string, speed int64, inFlight, maxInFlight *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(maxInFlight, max, current) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		var req request
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		switch req.Method {
+		case MethodTorrentGet:
+			var torrents []map[string]interface{}
+			for i, hash := range hashes {
+				torrents = append(torrents, map[string]interface{}{
+					"id":           i + 1,
+					"hashString":   hash,
+					"rateDownload": speed,
+					"totalSize":    100,
+				})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"result":    "success",
+				"arguments": map[string]interface{}{"torrents": torrents},
+			})
+		case MethodSessionStats:
+			_, _ = fmt.Fprintf(w, `{"result": "success", "arguments": {
+				"downloadSpeed": %d, "torrentCount": %d,
+				"cumulative-stats": {"downloadedBytes": 1000}
+			}}`, speed, len(hashes))
+		case MethodSessionGet:
+			_, _ = w.Write([]byte(`{"result": "success", "arguments": {"version": "3.00"}}`))
+		case MethodTorrentStart:
+			_, _ = w.Write([]byte(`{"result": "success"}`))
+		}
+	}))
+}
+
+func TestPool(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	alpha := newPoolDaemon([]string{"aaaa", "abab"}, 10, &inFlight, &maxInFlight)
+	defer alpha.Close()
+	beta := newPoolDaemon([]string{"bbbb"}, 5, &inFlight, &maxInFlight)
+	defer beta.Close()
+	gamma := newPoolDaemon(nil, 0, &inFlight, &maxInFlight)
+	gamma.Close()
+
+	clients := make(map[string]*Client)
+	for name, s := range map[string]*httptest.Server{"alpha": alpha, "beta": beta, "gamma": gamma} {
+		clients[name], _ = New(WithURL(s.URL))
+	}
+
+	t.Run("should sort daemon names", func(st *testing.T) {
+		pool := NewPool(clients)
+		assert.Equal(st, []string{"alpha", "beta", "gamma"}, pool.Names())
+
+		client, ok := pool.Client("beta")
+		assert.True(st, ok)
+		assert.Equal(st, clients["beta"], client)
+	})
+
+	t.Run("should limit concurrent requests", func(st *testing.T) {
+		atomic.StoreInt32(&maxInFlight, 0)
+
+		pool := NewPool(clients, WithConcurrency(1))
+		pool.SessionGet(context.Background())
+		assert.Equal(st, int32(1), atomic.LoadInt32(&maxInFlight))
+
+		atomic.StoreInt32(&maxInFlight, 0)
+
+		pool = NewPool(clients, WithConcurrency(3))
+		pool.SessionGet(context.Background())
+		assert.Equal(st, int32(2), atomic.LoadInt32(&maxInFlight))
+	})
+
+	t.Run("should return per daemon torrents and errors", func(st *testing.T) {
+		pool := NewPool(clients)
+		results := pool.TorrentGet(context.Background(), TorrentGet{})
+
+		assert.Len(st, results, 3)
+		assert.Equal(st, "alpha", results[0].Daemon)
+		assert.Len(st, results[0].Torrents, 2)
+		assert.NoError(st, results[0].Err)
+		assert.Equal(st, "beta", results[1].Daemon)
+		assert.Len(st, results[1].Torrents, 1)
+		assert.Equal(st, "gamma", results[2].Daemon)
+		assert.Error(st, results[2].Err)
+
+		totals := AggregateTorrents(results)
+		assert.Equal(st, TorrentTotals{
+			Daemons:      3,
+			Failed:       1,
+			Count:        3,
+			RateDownload: 25,
+			TotalSize:    300,
+		}, totals)
+	})
+
+	t.Run("should return per daemon sessions", func(st *testing.T) {
+		results := NewPool(clients).SessionGet(context.Background())
+		assert.Equal(st, "3.00", results[0].Session.Version)
+		assert.Equal(st, "3.00", results[1].Session.Version)
+		assert.Error(st, results[2].Err)
+	})
+
+	t.Run("should aggregate session stats", func(st *testing.T) {
+		results := NewPool(clients).SessionStats(context.Background())
+
+		total := AggregateStats(results)
+		assert.Equal(st, 3, total.Daemons)
+		assert.Equal(st, 1, total.Failed)
+		assert.Equal(st, int64(15), total.DownloadSpeed)
+		assert.Equal(st, int64(3), total.TorrentCount)
+		assert.Equal(st, int64(2000), total.CumulativeStats.DownloadedBytes)
+	})
+
+	t.Run("should route operations to the daemon owning the torrent", func(st *testing.T) {
+		pool := NewPool(clients)
+
+		var routed *Client
+		err := pool.Route(context.Background(), "BBBB", func(ctx context.Context, client *Client) error {
+			routed = client
+			return client.TorrentStart(ctx, Filter{Ids: []string{"bbbb"}})
+		})
+		assert.NoError(st, err)
+		assert.Equal(st, clients["beta"], routed)
+
+		name, err := pool.Locate(context.Background(), "abab")
+		assert.NoError(st, err)
+		assert.Equal(st, "alpha", name)
+	})
+
+	t.Run("should return an error when no daemon owns the torrent", func(st *testing.T) {
+		pool := NewPool(clients)
+
+		err := pool.Route(context.Background(), "cccc", func(ctx context.Context, client *Client) error {
+			return nil
+		})
+		assert.True(st, errors.Is(err, ErrTorrentNotFound))
+		assert.Contains(st, err.Error(), "gamma")
+	})
+
+	t.Run("should look again for torrents gone from the cached owner", func(st *testing.T) {
+		pool := NewPool(clients)
+		pool.owners["aaaa"] = "beta"
+
+		name, err := pool.Locate(context.Background(), "aaaa")
+		assert.NoError(st, err)
+		assert.Equal(st, "alpha", name)
+		assert.Equal(st, "alpha", pool.owners["aaaa"])
+	})
+
+	t.Run("should forget cached owners", func(st *testing.T) {
+		pool := NewPool(clients)
+		pool.owners["aaaa"] = "alpha"
+
+		pool.Forget("AAAA")
+		assert.NotContains(st, pool.owners, "aaaa")
+	})
+
+	t.Run("should stop waiting for a slot when the context is done", func(st *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results := NewPool(clients, WithConcurrency(1)).SessionGet(ctx)
+		assert.Len(st, results, 3)
+		for i, name := range []string{"alpha", "beta", "gamma"} {
+			assert.Equal(st, name, results[i].Daemon)
+			assert.True(st, errors.Is(results[i].Err, context.Canceled))
+		}
+	})
+}
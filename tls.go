@@ -0,0 +1,159 @@
+package transmission
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrCertificateMismatch = errors.New("server certificate does not match the pinned fingerprint")
+	ErrTLSTransport        = errors.New("tls options require the http client to use an *http.Transport")
+)
+
+type tlsOptions struct {
+	caFile      string
+	certFile    string
+	keyFile     string
+	serverName  string
+	fingerprint string
+}
+
+func (o tlsOptions) empty() bool {
+	return o == tlsOptions{}
+}
+
+// WithCAFile trusts the PEM encoded certificates of the given file, in
+// addition to the system pool, to verify the server certificate.
+func WithCAFile(path string) Option {
+	return func(c *Client) {
+		c.tls.caFile = path
+		c.useTLS = true
+	}
+}
+
+// WithClientCertificate presents the given PEM encoded certificate and key
+// to servers that require mutual tls.
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(c *Client) {
+		c.tls.certFile = certFile
+		c.tls.keyFile = keyFile
+		c.useTLS = true
+	}
+}
+
+// WithServerName overrides the name used to verify the server certificate,
+// useful when the daemon is reached by ip address.
+func WithServerName(name string) Option {
+	return func(c *Client) {
+		c.tls.serverName = name
+		c.useTLS = true
+	}
+}
+
+// WithCertificateFingerprint pins the server leaf certificate by its SHA-256
+// fingerprint (hex, colons optional). Without WithCAFile the pin replaces the
+// chain verification, which allows self-signed certificates.
+func WithCertificateFingerprint(fingerprint string) Option {
+	return func(c *Client) {
+		c.tls.fingerprint = fingerprint
+		c.useTLS = true
+	}
+}
+
+func (c *Client) configureTLS() error {
+	if c.tls.empty() {
+		return nil
+	}
+
+	var transport *http.Transport
+
+	switch t := c.HTTPClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return ErrTLSTransport
+	}
+
+	// the settings of a transport given with WithHTTPClient are kept, the
+	// options only replace the fields they control
+	config := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+
+	if config.MinVersion < tls.VersionTLS12 {
+		config.MinVersion = tls.VersionTLS12
+	}
+
+	if c.tls.serverName != "" {
+		config.ServerName = c.tls.serverName
+	}
+
+	if c.tls.caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		buf, err := ioutil.ReadFile(c.tls.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read ca file: %w", err)
+		}
+
+		if !pool.AppendCertsFromPEM(buf) {
+			return fmt.Errorf("no valid certificates found in ca file %s", c.tls.caFile)
+		}
+
+		config.RootCAs = pool
+	}
+
+	if c.tls.certFile != "" || c.tls.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.tls.certFile, c.tls.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.tls.fingerprint != "" {
+		pin, err := hex.DecodeString(strings.ReplaceAll(c.tls.fingerprint, ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			return fmt.Errorf("invalid sha-256 certificate fingerprint %q", c.tls.fingerprint)
+		}
+
+		// the chain is still verified when a custom ca was given
+		config.InsecureSkipVerify = config.RootCAs == nil
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return ErrCertificateMismatch
+			}
+
+			sum := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(sum[:], pin) {
+				return ErrCertificateMismatch
+			}
+
+			return nil
+		}
+	}
+
+	transport.TLSClientConfig = config
+
+	// copy the client so the one given with WithHTTPClient is not modified
+	httpClient := *c.HTTPClient
+	httpClient.Transport = transport
+	c.HTTPClient = &httpClient
+
+	return nil
+}
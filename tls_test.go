@@ -0,0 +1,231 @@
+package transmission
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writePEM(t *testing.T, dir, name, kind string, der []byte) string {
+	path := filepath.Join(dir, name)
+	buf := pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der})
+	if err := ioutil.WriteFile(path, buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// newClientCertificate generates a self-signed client certificate and
+// returns the paths of its PEM files.
+func newClientCertificate(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "transmission-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+
+	return cert, writePEM(t, dir, "client.crt", "CERTIFICATE", der), writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDer)
+}
+
+func newTLSServer() *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"result": "success"}`))
+	}))
+}
+
+func TestClient_TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transmission-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("should fail without trusting the server certificate", func(st *testing.T) {
+		s := newTLSServer()
+		defer s.Close()
+
+		client, err := New(WithURL(s.URL), WithServerName("example.com"))
+		assert.NoError(st, err)
+		assert.Error(st, client.SessionClose(context.Background()))
+	})
+
+	t.Run("should trust certificates from ca file", func(st *testing.T) {
+		s := newTLSServer()
+		defer s.Close()
+
+		caFile := writePEM(st, dir, "ca.crt", "CERTIFICATE", s.Certificate().Raw)
+		client, err := New(WithURL(s.URL), WithCAFile(caFile))
+		assert.NoError(st, err)
+		assert.NoError(st, client.SessionClose(context.Background()))
+	})
+
+	t.Run("should verify the overridden server name", func(st *testing.T) {
+		s := newTLSServer()
+		defer s.Close()
+
+		caFile := writePEM(st, dir, "ca.crt", "CERTIFICATE", s.Certificate().Raw)
+
+		client, _ := New(WithURL(s.URL), WithCAFile(caFile), WithServerName("example.com"))
+		assert.NoError(st, client.SessionClose(context.Background()))
+
+		client, _ = New(WithURL(s.URL), WithCAFile(caFile), WithServerName("unknown.com"))
+		assert.Error(st, client.SessionClose(context.Background()))
+	})
+
+	t.Run("should present client certificate to the server", func(st *testing.T) {
+		cert, certFile, keyFile := newClientCertificate(st, dir)
+
+		s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"result": "success"}`))
+		}))
+		pool := x509.NewCertPool()
+		pool.AddCert(cert)
+		s.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+		s.StartTLS()
+		defer s.Close()
+
+		caFile := writePEM(st, dir, "ca.crt", "CERTIFICATE", s.Certificate().Raw)
+
+		client, _ := New(WithURL(s.URL), WithCAFile(caFile))
+		assert.Error(st, client.SessionClose(context.Background()))
+
+		client, err := New(WithURL(s.URL), WithCAFile(caFile), WithClientCertificate(certFile, keyFile))
+		assert.NoError(st, err)
+		assert.NoError(st, client.SessionClose(context.Background()))
+	})
+
+	t.Run("should accept a pinned certificate", func(st *testing.T) {
+		s := newTLSServer()
+		defer s.Close()
+
+		sum := sha256.Sum256(s.Certificate().Raw)
+		client, err := New(WithURL(s.URL), WithCertificateFingerprint(hex.EncodeToString(sum[:])))
+		assert.NoError(st, err)
+		assert.NoError(st, client.SessionClose(context.Background()))
+	})
+
+	t.Run("should reject a certificate not matching the pin", func(st *testing.T) {
+		s := newTLSServer()
+		defer s.Close()
+
+		sum := sha256.Sum256([]byte("another certificate"))
+		client, err := New(WithURL(s.URL), WithCertificateFingerprint(hex.EncodeToString(sum[:])))
+		assert.NoError(st, err)
+
+		err = client.SessionClose(context.Background())
+		assert.Error(st, err)
+		assert.Contains(st, err.Error(), ErrCertificateMismatch.Error())
+	})
+
+	t.Run("should not modify the given http client", func(st *testing.T) {
+		s := newTLSServer()
+		defer s.Close()
+
+		httpClient := &http.Client{Timeout: time.Second}
+		caFile := writePEM(st, dir, "ca.crt", "CERTIFICATE", s.Certificate().Raw)
+		client, err := New(WithURL(s.URL), WithHTTPClient(httpClient), WithCAFile(caFile))
+		assert.NoError(st, err)
+		assert.Nil(st, httpClient.Transport)
+		assert.Equal(st, time.Second, client.HTTPClient.Timeout)
+		assert.NoError(st, client.SessionClose(context.Background()))
+	})
+
+	t.Run("should keep the tls config of the given transport", func(st *testing.T) {
+		s := newTLSServer()
+		defer s.Close()
+
+		_, certFile, keyFile := newClientCertificate(st, dir)
+
+		pool := x509.NewCertPool()
+		pool.AddCert(s.Certificate())
+		transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: "example.com"}}
+
+		client, err := New(WithURL(s.URL), WithHTTPClient(&http.Client{Transport: transport}), WithClientCertificate(certFile, keyFile))
+		assert.NoError(st, err)
+		assert.NoError(st, client.SessionClose(context.Background()))
+
+		config := client.HTTPClient.Transport.(*http.Transport).TLSClientConfig
+		assert.Equal(st, pool, config.RootCAs)
+		assert.Equal(st, "example.com", config.ServerName)
+		assert.Len(st, config.Certificates, 1)
+		assert.Nil(st, transport.TLSClientConfig.Certificates)
+	})
+
+	t.Run("should switch the scheme to https", func(st *testing.T) {
+		client, err := New(WithHost("nas"), WithServerName("nas.local"))
+		assert.NoError(st, err)
+		assert.Equal(st, "https://nas:9091/transmission/rpc", client.URL)
+	})
+
+	invalid := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "should fail with missing ca file", opt: WithCAFile(filepath.Join(dir, "missing.crt"))},
+		{name: "should fail with missing client certificate", opt: WithClientCertificate("missing.crt", "missing.key")},
+		{name: "should fail with invalid fingerprint", opt: WithCertificateFingerprint("zz:11")},
+	}
+
+	for _, test := range invalid {
+		t.Run(test.name, func(st *testing.T) {
+			// nolint
+			client, err := New(test.opt)
+			assert.Nil(st, client)
+			assert.Error(st, err)
+		})
+	}
+
+	t.Run("should fail with a custom round tripper", func(st *testing.T) {
+		httpClient := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+		client, err := New(WithHTTPClient(httpClient), WithServerName("nas"))
+		assert.Nil(st, client)
+		assert.Equal(st, ErrTLSTransport, err)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
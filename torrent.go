@@ -1,5 +1,16 @@
 package transmission
 
+// Torrent status values, as sent by the daemon in Torrent.Status.
+const (
+	StatusStopped int64 = iota
+	StatusCheckWait
+	StatusCheck
+	StatusDownloadWait
+	StatusDownload
+	StatusSeedWait
+	StatusSeed
+)
+
 type File struct {
 	BytesCompleted int64  `json:"bytesCompleted,omitempty"`
 	Length         int64  `json:"length,omitempty"`
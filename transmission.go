@@ -7,7 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 type Method string
@@ -39,15 +44,29 @@ const (
 	MethodPortTest        Method = "port-test"
 	MethodBlockListUpdate Method = "blocklist-update"
 
+	// MethodPing is not part of the spec, see Client.Ping
+	MethodPing Method = "ping"
+
 	ResponseResultSuccess = "success"
 	SessionIDHeader       = "X-Transmission-Session-Id"
 
 	DefaultMaxRetries = 2
 	MaxRetries        = 10
+
+	Version          = "0.1.0"
+	DefaultUserAgent = "transmission/" + Version
+
+	DefaultHost    = "localhost"
+	DefaultPort    = 9091
+	DefaultRPCPath = "/transmission/rpc"
 )
 
 var (
 	ErrInvalidSessionID = errors.New("invalid session-id header")
+	ErrInvalidURL       = errors.New("invalid rpc url")
+	ErrNonJSONResponse  = errors.New("response is not a valid rpc json document")
+
+	errTransport = errors.New("unexpected error sending http request")
 )
 
 type response struct {
@@ -61,6 +80,8 @@ type request struct {
 	Arguments  interface{} `json:"arguments,omitempty"` // object of key/value pairs
 	Tag        int64       `json:"tag,omitempty"`       // number used by clients to track responses (same request tag value)
 	AvoidRetry bool        `json:"-"`
+	// Endpoint sends the request to this url only, without failing over
+	Endpoint string `json:"-"`
 }
 
 type Filter struct {
@@ -200,14 +221,63 @@ type Client struct {
 	SessionID  string
 	HTTPClient *http.Client
 	MaxRetries int
+
+	host    string
+	port    int
+	rpcPath string
+	useTLS  bool
+	tls     tlsOptions
+
+	userAgent      string
+	headers        http.Header
+	headerProvider HeaderProvider
+	decodeMode     DecodeMode
+	warningHandler func([]DecodeWarning)
+
+	// mu guards URL and SessionID, which follow the active endpoint
+	mu        sync.Mutex
+	endpoints []*endpoint
+	active    int
 }
 
+// WithURL sets the rpc endpoint. Partial values are accepted: a bare host
+// (e.g. "nas" or "nas:9092") gets the default scheme and port, and an empty
+// path gets DefaultRPCPath.
 func WithURL(url string) Option {
 	return func(c *Client) {
 		c.URL = url
 	}
 }
 
+// WithHost overrides the host of the rpc endpoint.
+func WithHost(host string) Option {
+	return func(c *Client) {
+		c.host = host
+	}
+}
+
+// WithPort overrides the port of the rpc endpoint.
+func WithPort(port int) Option {
+	return func(c *Client) {
+		c.port = port
+	}
+}
+
+// WithRPCPath overrides the path of the rpc endpoint, "/" for a daemon
+// served at the root of a reverse proxy.
+func WithRPCPath(path string) Option {
+	return func(c *Client) {
+		c.rpcPath = path
+	}
+}
+
+// WithTLS forces the https scheme on the rpc endpoint.
+func WithTLS() Option {
+	return func(c *Client) {
+		c.useTLS = true
+	}
+}
+
 func WithBasicAuth(user, password string) Option {
 	return func(c *Client) {
 		c.Username = user
@@ -239,14 +309,143 @@ func WithMaxRetries(maxRetries int) Option {
 	}
 }
 
-func New(opts ...Option) *Client {
-	client := Client{HTTPClient: &http.Client{}, MaxRetries: DefaultMaxRetries}
+// New creates a client with the given options. It returns ErrInvalidURL
+// when the rpc endpoint can not be built from them, or an error when the tls
+// options can not be loaded.
+func New(opts ...Option) (*Client, error) {
+	client := Client{HTTPClient: &http.Client{}, MaxRetries: DefaultMaxRetries, userAgent: DefaultUserAgent}
 
 	for _, o := range opts {
 		o(&client)
 	}
 
-	return &client
+	if err := client.resolveURL(); err != nil {
+		return nil, err
+	}
+
+	if err := client.resolveEndpoints(); err != nil {
+		return nil, err
+	}
+
+	if err := client.configureTLS(); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+func (c *Client) resolveURL() error {
+	u, err := c.buildURL(c.URL)
+	if err != nil {
+		return err
+	}
+
+	c.URL = u
+
+	return nil
+}
+
+// buildURL builds the final rpc url from raw and the individual host, port,
+// path and tls options, filling in the defaults.
+func (c *Client) buildURL(raw string) (string, error) {
+	original := raw
+	if raw == "" {
+		raw = DefaultHost
+	}
+
+	// a value without scheme is treated as host[:port][/path], and it is the
+	// only case where the transmission default port is applied
+	bare := !strings.Contains(raw, "://")
+	if bare {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	if c.useTLS {
+		u.Scheme = "https"
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%w: unsupported scheme %q", ErrInvalidURL, u.Scheme)
+	}
+
+	host, port := u.Hostname(), u.Port()
+	if c.host != "" {
+		host = c.host
+		if h, p, err := net.SplitHostPort(c.host); err == nil {
+			host, port = h, p
+		}
+	}
+
+	if c.port != 0 {
+		port = strconv.Itoa(c.port)
+	} else if port == "" && bare {
+		port = strconv.Itoa(DefaultPort)
+	}
+
+	if host == "" {
+		return "", fmt.Errorf("%w: missing host in %q", ErrInvalidURL, original)
+	}
+
+	if p, err := strconv.Atoi(port); port != "" && (err != nil || p <= 0 || p > 65535) {
+		return "", fmt.Errorf("%w: invalid port %q", ErrInvalidURL, port)
+	}
+
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	// an explicit path, even "/" behind a reverse proxy, is used as given
+	switch {
+	case c.rpcPath != "":
+		u.Path = c.rpcPath
+	case u.Path == "":
+		u.Path = DefaultRPCPath
+	}
+
+	if !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+
+	// credentials embedded in the url are moved to basic auth
+	if u.User != nil {
+		if c.Username == "" {
+			c.Username = u.User.Username()
+			c.Password, _ = u.User.Password()
+		}
+		u.User = nil
+	}
+
+	u.RawPath = ""
+
+	return u.String(), nil
+}
+
+// transportError is returned when the daemon could not be reached. It
+// matches errTransport and unwraps to the http client error.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return fmt.Sprintf("%s: %+v", errTransport, e.err)
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+func (e *transportError) Is(target error) bool {
+	return target == errTransport
 }
 
 func fillStruct(base interface{}, target interface{}) error {
@@ -258,39 +457,64 @@ func fillStruct(base interface{}, target interface{}) error {
 	return json.Unmarshal(buf, target)
 }
 
-func (c *Client) doRequest(ctx context.Context, body []byte, maxRetries int) (*http.Response, error) {
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewBuffer(body))
+func (c *Client) doRequest(ctx context.Context, url string, body []byte, maxRetries int) (*http.Response, error) {
+	c.mu.Lock()
+	sessionID := c.sessionID(url)
+	c.mu.Unlock()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request with context: %+v", err)
 	}
 
-	if c.Password != "" && c.Username != "" {
+	// an Authorization header, e.g. a bearer token, replaces basic auth
+	if c.Password != "" && c.Username != "" && c.headers.Get("Authorization") == "" {
 		request.SetBasicAuth(c.Username, c.Password)
 	}
 
-	request.Header.Set("User-Agent", "transmission")
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set(SessionIDHeader, c.SessionID)
+	if err := c.setHeaders(ctx, request.Header, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to get request headers: %w", err)
+	}
+
 	resp, err := c.HTTPClient.Do(request)
 
 	if err != nil {
-		return nil, fmt.Errorf("unexpected error sending http request: %+v", err)
+		return nil, &transportError{err: err}
 	}
 
 	if resp.StatusCode == http.StatusConflict {
-		c.SessionID = resp.Header.Get(SessionIDHeader)
+		_ = resp.Body.Close()
+
+		c.mu.Lock()
+		c.setSessionID(url, resp.Header.Get(SessionIDHeader))
+		c.mu.Unlock()
 
 		if maxRetries-1 <= 0 {
 			return nil, ErrInvalidSessionID
 		}
 
-		return c.doRequest(ctx, body, maxRetries-1)
+		return c.doRequest(ctx, url, body, maxRetries-1)
 	}
 
 	return resp, nil
 }
 
 func (c *Client) fetch(ctx context.Context, request request) (*response, error) {
+	res, err := c.roundTrip(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Result != ResponseResultSuccess {
+		return nil, fmt.Errorf("unexpected result: %s", res.Result)
+	}
+
+	return res, nil
+}
+
+// roundTrip sends the request and parses the rpc response, whatever its
+// result.
+func (c *Client) roundTrip(ctx context.Context, request request) (*response, error) {
 	body, err := json.Marshal(&request)
 	if err != nil {
 		return nil, err
@@ -302,33 +526,40 @@ func (c *Client) fetch(ctx context.Context, request request) (*response, error)
 		maxRetries = 1
 	}
 
-	resp, err := c.doRequest(ctx, body, maxRetries)
+	resp, err := c.send(ctx, body, maxRetries, request.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 
+	defer resp.Body.Close()
+
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-
 	var res response
 	if err := json.Unmarshal(buf, &res); err != nil {
-		return nil, err
-	}
-
-	if res.Result != ResponseResultSuccess {
-		return nil, fmt.Errorf("unexpected result: %s", res.Result)
+		// usually the url points to the web interface or a proxy error page
+		return nil, fmt.Errorf(
+			"%w (status %d, content-type %q): %v",
+			ErrNonJSONResponse, resp.StatusCode, resp.Header.Get("Content-Type"), err,
+		)
 	}
 
 	return &res, nil
 }
 
 func (c *Client) Ping(ctx context.Context) error {
-	// this is just a hack to retrieve a valid session id token
-	_, err := c.fetch(ctx, request{Method: "ping", AvoidRetry: true})
+	return c.ping(ctx, "")
+}
+
+func (c *Client) ping(ctx context.Context, endpoint string) error {
+	// this is just a hack to retrieve a valid session id token: the daemon
+	// answers the unknown method with an error result, or with the session
+	// handshake, both telling it is reachable. Transport errors and non json
+	// answers, e.g. a 401 page, are not.
+	_, err := c.roundTrip(ctx, request{Method: MethodPing, AvoidRetry: true, Endpoint: endpoint})
 	if errors.Is(err, ErrInvalidSessionID) {
 		return nil
 	}
@@ -370,12 +601,16 @@ func (c *Client) TorrentGet(ctx context.Context, args TorrentGet) ([]Torrent, er
 		return torrents, err
 	}
 
+	if err := c.checkArguments(MethodTorrentGet, response.Arguments); err != nil {
+		return torrents, err
+	}
+
 	list, ok := response.Arguments["torrents"]
 	if !ok {
 		return torrents, nil
 	}
 
-	err = fillStruct(list, &torrents)
+	err = c.decode(MethodTorrentGet, list, &torrents)
 
 	return torrents, err
 }
@@ -389,7 +624,11 @@ func (c *Client) TorrentRename(ctx context.Context, args TorrentRename) (Torrent
 		return torrent, err
 	}
 
-	err = fillStruct(resp.Arguments, &torrent)
+	if err := c.checkArguments(MethodTorrentRename, resp.Arguments); err != nil {
+		return torrent, err
+	}
+
+	err = c.decode(MethodTorrentRename, resp.Arguments, &torrent)
 
 	return torrent, err
 }
@@ -399,29 +638,49 @@ func (c *Client) TorrentSet(ctx context.Context, args TorrentSet) error {
 	return err
 }
 
-func (c *Client) TorrentAdd(ctx context.Context, args TorrentAdd) (Torrent, error) {
-	var torrent Torrent
+// AddResult is the torrent answered by torrent-add. Duplicate is set when the
+// daemon already had it, in which case nothing was added and the arguments
+// (e.g. download dir or file priorities) were ignored.
+type AddResult struct {
+	Torrent
+	Duplicate bool
+}
 
+func (c *Client) TorrentAdd(ctx context.Context, args TorrentAdd) (AddResult, error) {
 	resp, err := c.fetch(ctx, request{Method: MethodTorrentAdd, Arguments: args})
 	if err != nil {
-		return torrent, err
+		return AddResult{}, err
 	}
 
-	var torrentResponse interface{}
+	return c.decodeTorrentAdd(resp.Arguments)
+}
+
+func (c *Client) decodeTorrentAdd(arguments map[string]interface{}) (AddResult, error) {
+	var (
+		result          AddResult
+		torrentResponse interface{}
+		found           bool
+	)
 
 	// torrent-duplicate is coming when torrent it's already added
 	// to the list (same magnet link)
-	if duplicated, ok := resp.Arguments["torrent-duplicate"]; ok {
-		torrentResponse = duplicated
+	if duplicated, ok := arguments["torrent-duplicate"]; ok {
+		torrentResponse, found = duplicated, true
+		result.Duplicate = true
 	}
 
-	if added, ok := resp.Arguments["torrent-added"]; ok {
-		torrentResponse = added
+	if added, ok := arguments["torrent-added"]; ok {
+		torrentResponse, found = added, true
+		result.Duplicate = false
 	}
 
-	err = fillStruct(torrentResponse, &torrent)
+	if !found && c.decodeMode == DecodeStrict {
+		return result, &DecodeError{Field: "torrent-added", Value: "null", Err: ErrMalformedValue}
+	}
 
-	return torrent, err
+	err := c.decode(MethodTorrentAdd, torrentResponse, &result.Torrent)
+
+	return result, err
 }
 
 func (c *Client) TorrentRemove(ctx context.Context, args TorrentRemove) error {
@@ -447,7 +706,11 @@ func (c *Client) SessionGet(ctx context.Context) (Session, error) {
 		return session, err
 	}
 
-	err = fillStruct(resp.Arguments, &session)
+	if err := c.checkArguments(MethodSessionGet, resp.Arguments); err != nil {
+		return session, err
+	}
+
+	err = c.decode(MethodSessionGet, resp.Arguments, &session)
 
 	return session, err
 }
@@ -460,7 +723,11 @@ func (c *Client) SessionStats(ctx context.Context) (SessionStats, error) {
 		return stats, err
 	}
 
-	err = fillStruct(resp.Arguments, &stats)
+	if err := c.checkArguments(MethodSessionStats, resp.Arguments); err != nil {
+		return stats, err
+	}
+
+	err = c.decode(MethodSessionStats, resp.Arguments, &stats)
 
 	return stats, err
 }
@@ -498,7 +765,11 @@ func (c *Client) FreeSpace(ctx context.Context, args FreeSpace) (FreeSpace, erro
 		return free, err
 	}
 
-	err = fillStruct(resp.Arguments, &free)
+	if err := c.checkArguments(MethodFreeSpace, resp.Arguments); err != nil {
+		return free, err
+	}
+
+	err = c.decode(MethodFreeSpace, resp.Arguments, &free)
 
 	return free, err
 }
@@ -511,7 +782,11 @@ func (c *Client) PortCheck(ctx context.Context) (PortCheck, error) {
 		return port, err
 	}
 
-	err = fillStruct(resp.Arguments, &port)
+	if err := c.checkArguments(MethodPortTest, resp.Arguments); err != nil {
+		return port, err
+	}
+
+	err = c.decode(MethodPortTest, resp.Arguments, &port)
 
 	return port, err
 }
@@ -524,7 +799,11 @@ func (c *Client) BlockListUpdate(ctx context.Context) (BlockList, error) {
 		return blockList, err
 	}
 
-	err = fillStruct(resp.Arguments, &blockList)
+	if err := c.checkArguments(MethodBlockListUpdate, resp.Arguments); err != nil {
+		return blockList, err
+	}
+
+	err = c.decode(MethodBlockListUpdate, resp.Arguments, &blockList)
 
 	return blockList, err
 }
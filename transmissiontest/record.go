@@ -0,0 +1,337 @@
+package transmissiontest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/mfuentesg/transmission"
+)
+
+const (
+	ReplayURL = "http://replay.invalid/transmission/rpc"
+
+	scrubbed = "[scrubbed]"
+)
+
+var (
+	ErrNoInteraction = errors.New("no recorded interaction matches the request")
+)
+
+// Interaction is a recorded rpc request and its response. Only the request
+// arguments are kept: credentials and session ids live in headers, which are
+// never recorded.
+type Interaction struct {
+	Method      transmission.Method `json:"method"`
+	Arguments   json.RawMessage     `json:"arguments,omitempty"`
+	Status      int                 `json:"status"`
+	ContentType string              `json:"content-type,omitempty"`
+	Response    json.RawMessage     `json:"response"`
+}
+
+// Fixture is the content of a fixtures file. DaemonVersion is taken from the
+// first recorded session-get response, and helps building a corpus across
+// daemon versions.
+type Fixture struct {
+	DaemonVersion string        `json:"daemon-version,omitempty"`
+	Interactions  []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper capturing the rpc interactions going
+// through Transport (http.DefaultTransport when nil). Session-id handshakes
+// are not recorded.
+type Recorder struct {
+	Transport http.RoundTripper
+
+	// Scrub is called on every interaction before storing it, after
+	// ScrubInteraction, to remove other sensitive values from arguments or
+	// responses.
+	Scrub func(*Interaction)
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusConflict {
+		return resp, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	if err != nil {
+		return resp, nil
+	}
+
+	var rpc struct {
+		Method    transmission.Method `json:"method"`
+		Arguments json.RawMessage     `json:"arguments"`
+	}
+
+	if err := json.Unmarshal(body, &rpc); err != nil {
+		return resp, nil
+	}
+
+	interaction := Interaction{
+		Method:      rpc.Method,
+		Arguments:   rpc.Arguments,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Response:    normalizeResponse(respBody),
+	}
+
+	ScrubInteraction(&interaction)
+	if r.Scrub != nil {
+		r.Scrub(&interaction)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.fixture.DaemonVersion == "" && rpc.Method == transmission.MethodSessionGet {
+		var session struct {
+			Arguments struct {
+				Version string `json:"version"`
+			} `json:"arguments"`
+		}
+		if json.Unmarshal(respBody, &session) == nil {
+			r.fixture.DaemonVersion = session.Arguments.Version
+		}
+	}
+
+	r.fixture.Interactions = append(r.fixture.Interactions, interaction)
+
+	return resp, nil
+}
+
+// Fixture returns what was recorded so far.
+func (r *Recorder) Fixture() Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fixture := r.fixture
+	fixture.Interactions = append([]Interaction(nil), r.fixture.Interactions...)
+
+	return fixture
+}
+
+// Save writes the recorded fixture as indented json.
+func (r *Recorder) Save(path string) error {
+	buf, err := json.MarshalIndent(r.Fixture(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(buf, '\n'), 0644)
+}
+
+// normalizeResponse keeps json bodies as they are, and stores anything else
+// (e.g. html error pages) as a json string.
+func normalizeResponse(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return append(json.RawMessage(nil), body...)
+	}
+
+	buf, _ := json.Marshal(string(body))
+
+	return buf
+}
+
+// Replayer is an http.RoundTripper answering rpc requests with recorded
+// interactions, matched by method and arguments. Each interaction is used
+// once and in order, unless AllowReuse is set.
+type Replayer struct {
+	AllowReuse bool
+
+	mu      sync.Mutex
+	fixture Fixture
+	used    []bool
+}
+
+func NewReplayer(fixture Fixture) *Replayer {
+	return &Replayer{fixture: fixture, used: make([]bool, len(fixture.Interactions))}
+}
+
+// LoadReplayer reads a fixtures file written by Recorder.Save.
+func LoadReplayer(path string) (*Replayer, error) {
+	var fixture Fixture
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixtures file %s: %w", path, err)
+	}
+
+	return NewReplayer(fixture), nil
+}
+
+// Client returns a client sending its requests to the replayer.
+func (r *Replayer) Client(opts ...transmission.Option) *transmission.Client {
+	base := []transmission.Option{
+		transmission.WithURL(ReplayURL),
+		transmission.WithHTTPClient(&http.Client{Transport: r}),
+	}
+
+	client, err := transmission.New(append(base, opts...)...)
+	if err != nil {
+		panic(fmt.Sprintf("transmissiontest: invalid client options: %v", err))
+	}
+
+	return client
+}
+
+// DaemonVersion returns the daemon version of the replayed fixture.
+func (r *Replayer) DaemonVersion() string {
+	return r.fixture.DaemonVersion
+}
+
+// Pending returns the interactions that were not replayed yet.
+func (r *Replayer) Pending() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []Interaction
+	for i, interaction := range r.fixture.Interactions {
+		if !r.used[i] {
+			pending = append(pending, interaction)
+		}
+	}
+
+	return pending
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var rpc struct {
+		Method    transmission.Method `json:"method"`
+		Arguments json.RawMessage     `json:"arguments"`
+	}
+
+	if req.Body != nil {
+		defer req.Body.Close()
+		if err := json.NewDecoder(req.Body).Decode(&rpc); err != nil {
+			return nil, fmt.Errorf("invalid rpc request: %w", err)
+		}
+	}
+
+	// the arguments are compared as they were recorded
+	interaction, ok := r.match(rpc.Method, scrubJSON(rpc.Arguments, true))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, rpc.Method, rpc.Arguments)
+	}
+
+	header := make(http.Header)
+	if interaction.ContentType != "" {
+		header.Set("Content-Type", interaction.ContentType)
+	}
+
+	body := []byte(interaction.Response)
+
+	// non-json bodies were stored as json strings
+	var text string
+	if json.Unmarshal(body, &text) == nil {
+		body = []byte(text)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Replayer) match(method transmission.Method, arguments json.RawMessage) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reused := -1
+
+	for i, interaction := range r.fixture.Interactions {
+		if interaction.Method != method || !sameJSON(interaction.Arguments, arguments) {
+			continue
+		}
+
+		if !r.used[i] {
+			r.used[i] = true
+			return interaction, true
+		}
+
+		if r.AllowReuse {
+			reused = i
+		}
+	}
+
+	if reused >= 0 {
+		return r.fixture.Interactions[reused], true
+	}
+
+	return Interaction{}, false
+}
+
+// sameJSON compares two json documents ignoring formatting and key order,
+// and the scrubbed values of recorded arguments.
+func sameJSON(recorded, actual json.RawMessage) bool {
+	var a, b interface{}
+
+	if len(recorded) > 0 {
+		if json.Unmarshal(recorded, &a) != nil {
+			return false
+		}
+	}
+
+	if len(actual) > 0 {
+		if json.Unmarshal(actual, &b) != nil {
+			return false
+		}
+	}
+
+	if am, ok := a.(map[string]interface{}); ok {
+		if bm, ok := b.(map[string]interface{}); ok {
+			for key, value := range am {
+				if value == scrubbed {
+					if _, present := bm[key]; present {
+						bm[key] = scrubbed
+					}
+				}
+			}
+		}
+	}
+
+	return reflect.DeepEqual(a, b)
+}
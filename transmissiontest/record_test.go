@@ -0,0 +1,200 @@
+package transmissiontest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transmissiontest-record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	fixtures := filepath.Join(dir, "fixtures.json")
+
+	s := NewServer(
+		WithCredentials("admin", "very-secret"),
+		WithSessionID("secret-session-id"),
+		WithSession(transmission.Session{Version: "3.00 (bb6b5a062e)"}),
+	)
+	s.AddTorrent(transmission.Torrent{Name: "debian.iso", HashString: "aaaa"})
+
+	recorder := NewRecorder(nil)
+	client := s.Client(transmission.WithHTTPClient(&http.Client{Transport: recorder}))
+
+	_, err = client.SessionGet(ctx)
+	assert.NoError(t, err)
+
+	recorded, err := client.TorrentGet(ctx, transmission.TorrentGet{Ids: []int64{1}, Fields: []string{"id", "name"}})
+	assert.NoError(t, err)
+
+	_, err = client.TorrentAdd(ctx, transmission.TorrentAdd{
+		Filename: "https://tracker.example.com/file.torrent",
+		Cookies:  "uid=1; pass=tracker-secret",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.TorrentStop(ctx, transmission.Filter{Ids: []int64{1}}))
+	assert.NoError(t, recorder.Save(fixtures))
+	s.Close()
+
+	t.Run("should record interactions without credentials", func(st *testing.T) {
+		fixture := recorder.Fixture()
+		assert.Equal(st, "3.00 (bb6b5a062e)", fixture.DaemonVersion)
+		assert.Len(st, fixture.Interactions, 4)
+		assert.Equal(st, transmission.MethodSessionGet, fixture.Interactions[0].Method)
+
+		buf, err := ioutil.ReadFile(fixtures)
+		assert.NoError(st, err)
+		content := string(buf)
+		assert.NotContains(st, content, "very-secret")
+		assert.NotContains(st, content, "secret-session-id")
+		assert.NotContains(st, content, "tracker-secret")
+	})
+
+	t.Run("should replay recorded interactions", func(st *testing.T) {
+		replayer, err := LoadReplayer(fixtures)
+		assert.NoError(st, err)
+		assert.Equal(st, "3.00 (bb6b5a062e)", replayer.DaemonVersion())
+
+		client := replayer.Client()
+
+		// the order of unrelated requests does not matter
+		assert.NoError(st, client.TorrentStop(ctx, transmission.Filter{Ids: []int64{1}}))
+
+		torrents, err := client.TorrentGet(ctx, transmission.TorrentGet{Ids: []int64{1}, Fields: []string{"id", "name"}})
+		assert.NoError(st, err)
+		assert.Equal(st, recorded, torrents)
+
+		session, err := client.SessionGet(ctx)
+		assert.NoError(st, err)
+		assert.Equal(st, "3.00 (bb6b5a062e)", session.Version)
+
+		_, err = client.TorrentAdd(ctx, transmission.TorrentAdd{
+			Filename: "https://tracker.example.com/file.torrent",
+			Cookies:  "uid=2; pass=other",
+		})
+		assert.NoError(st, err)
+		assert.Empty(st, replayer.Pending())
+
+		// interactions are consumed
+		_, err = client.SessionGet(ctx)
+		assert.True(st, errors.Is(err, ErrNoInteraction))
+	})
+
+	t.Run("should not match different arguments", func(st *testing.T) {
+		replayer, _ := LoadReplayer(fixtures)
+
+		_, err := replayer.Client().TorrentGet(ctx, transmission.TorrentGet{Ids: []int64{2}, Fields: []string{"id", "name"}})
+		assert.True(st, errors.Is(err, ErrNoInteraction))
+		assert.Len(st, replayer.Pending(), 4)
+	})
+
+	t.Run("should reuse interactions when allowed", func(st *testing.T) {
+		replayer, _ := LoadReplayer(fixtures)
+		replayer.AllowReuse = true
+
+		client := replayer.Client()
+		for i := 0; i < 3; i++ {
+			_, err := client.SessionGet(ctx)
+			assert.NoError(st, err)
+		}
+	})
+
+	t.Run("should apply custom scrubbing", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		recorder := NewRecorder(nil)
+		recorder.Scrub = func(interaction *Interaction) {
+			interaction.Response = []byte(strings.Replace(string(interaction.Response), "/downloads", "/redacted", 1))
+		}
+
+		client := s.Client(transmission.WithHTTPClient(&http.Client{Transport: recorder}))
+		_, _ = client.SessionGet(ctx)
+
+		assert.Contains(st, string(recorder.Fixture().Interactions[0].Response), "/redacted")
+	})
+
+	t.Run("should scrub tracker passkeys", func(st *testing.T) {
+		const passkey = "0123456789abcdef0123456789abcdef"
+
+		s := NewServer()
+		defer s.Close()
+		s.AddTorrent(transmission.Torrent{
+			Name:       "private.iso",
+			HashString: "bbbb",
+			MagnetLink: "magnet:?xt=urn:btih:bbbb&tr=" + url.QueryEscape("https://tracker.example.com/announce?passkey="+passkey),
+			Trackers:   []transmission.Tracker{{Announce: "https://tracker.example.com/" + passkey + "/announce"}},
+			TrackerStats: []transmission.TrackerStat{
+				{Announce: "https://user:" + passkey + "@tracker.example.com/announce", Host: "tracker.example.com"},
+			},
+		})
+
+		path := filepath.Join(dir, "private.json")
+		args := transmission.TorrentGet{Fields: []string{"id", "magnetLink", "trackers", "trackerStats"}}
+		add := transmission.TorrentAdd{Filename: "https://tracker.example.com/download.php?id=1&passkey=" + passkey}
+
+		recorder := NewRecorder(nil)
+		client := s.Client(transmission.WithHTTPClient(&http.Client{Transport: recorder}))
+		_, err := client.TorrentGet(ctx, args)
+		assert.NoError(st, err)
+		_, err = client.TorrentAdd(ctx, add)
+		assert.NoError(st, err)
+		assert.NoError(st, recorder.Save(path))
+
+		buf, err := ioutil.ReadFile(path)
+		assert.NoError(st, err)
+		content := string(buf)
+		assert.NotContains(st, content, passkey)
+		assert.Contains(st, content, "https://tracker.example.com/scrubbed/announce")
+		assert.Contains(st, content, "https://scrubbed@tracker.example.com/announce")
+		assert.Contains(st, content, "magnet:?xt=urn:btih:bbbb")
+
+		replayer, err := LoadReplayer(path)
+		assert.NoError(st, err)
+		torrents, err := replayer.Client().TorrentGet(ctx, args)
+		assert.NoError(st, err)
+		assert.Equal(st, "tracker.example.com", torrents[0].TrackerStats[0].Host)
+		_, err = replayer.Client().TorrentAdd(ctx, add)
+		assert.NoError(st, err)
+	})
+
+	t.Run("should replay non json responses", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+		s.InjectFault(Fault{Status: http.StatusBadGateway})
+
+		recorder := NewRecorder(nil)
+		client := s.Client(transmission.WithHTTPClient(&http.Client{Transport: recorder}))
+		_, err := client.SessionGet(ctx)
+		assert.True(st, errors.Is(err, transmission.ErrNonJSONResponse))
+
+		_, err = NewReplayer(recorder.Fixture()).Client().SessionGet(ctx)
+		assert.True(st, errors.Is(err, transmission.ErrNonJSONResponse))
+		assert.Contains(st, err.Error(), "status 502")
+	})
+
+	t.Run("should fail loading invalid fixtures", func(st *testing.T) {
+		_, err := LoadReplayer(filepath.Join(dir, "missing.json"))
+		assert.Error(st, err)
+
+		invalid := filepath.Join(dir, "invalid.json")
+		_ = ioutil.WriteFile(invalid, []byte("{"), 0644)
+		_, err = LoadReplayer(invalid)
+		assert.Error(st, err)
+	})
+}
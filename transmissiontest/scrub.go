@@ -0,0 +1,206 @@
+package transmissiontest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// scrubbedPart replaces the secret parts of urls, which can not hold the
+// scrubbed marker without escaping.
+const scrubbedPart = "scrubbed"
+
+// urlKeys are the request and response fields holding tracker urls or magnet
+// links, whose credentials are scrubbed. Torrent-add filenames can be private
+// tracker urls too.
+var urlKeys = map[string]bool{
+	"announce":       true,
+	"scrape":         true,
+	"trackers":       true,
+	"trackerStats":   true,
+	"trackerAdd":     true,
+	"trackerReplace": true,
+	"trackerList":    true,
+	"magnetLink":     true,
+	"filename":       true,
+}
+
+// magnetURLParams are the magnet link parameters holding urls.
+var magnetURLParams = map[string]bool{"tr": true, "ws": true, "as": true, "xs": true}
+
+// ScrubInteraction removes the tracker credentials from the arguments and the
+// response of an interaction: torrent-add cookies are replaced, and the
+// userinfo, query values and passkey path segments of tracker urls and magnet
+// links are scrubbed with ScrubURL. Recorder applies it before Recorder.Scrub,
+// which can extend it.
+func ScrubInteraction(interaction *Interaction) {
+	interaction.Arguments = scrubJSON(interaction.Arguments, true)
+	interaction.Response = scrubJSON(interaction.Response, false)
+}
+
+// scrubJSON returns the scrubbed document, or doc itself when there is
+// nothing to scrub so the recorded bytes are kept as they are.
+func scrubJSON(doc json.RawMessage, arguments bool) json.RawMessage {
+	if len(doc) == 0 {
+		return doc
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.UseNumber()
+
+	var value interface{}
+	if decoder.Decode(&value) != nil {
+		return doc
+	}
+
+	changed := false
+
+	if object, ok := value.(map[string]interface{}); ok && arguments {
+		// cookies usually carry the tracker login
+		if _, ok := object["cookies"]; ok {
+			object["cookies"] = scrubbed
+			changed = true
+		}
+	}
+
+	value = scrubValue(value, false, &changed)
+	if !changed {
+		return doc
+	}
+
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return doc
+	}
+
+	return buf
+}
+
+// scrubValue walks value, scrubbing the strings found below a url field.
+func scrubValue(value interface{}, inURLField bool, changed *bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = scrubValue(item, inURLField || urlKeys[key], changed)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrubValue(item, inURLField, changed)
+		}
+	case string:
+		if !inURLField {
+			return v
+		}
+
+		// trackerList holds one url per line
+		lines := strings.Split(v, "\n")
+		for i, line := range lines {
+			lines[i] = ScrubURL(line)
+		}
+
+		if scrubbedValue := strings.Join(lines, "\n"); scrubbedValue != v {
+			*changed = true
+			return scrubbedValue
+		}
+	}
+
+	return value
+}
+
+// ScrubURL hides the credentials of a tracker url, or of the tracker urls of
+// a magnet link: userinfo, query values and path segments looking like a
+// passkey. Other values, e.g. local paths, are returned unchanged.
+func ScrubURL(raw string) string {
+	if strings.HasPrefix(raw, "magnet:?") {
+		return scrubMagnet(raw)
+	}
+
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return raw
+	}
+
+	changed := false
+
+	if u.User != nil {
+		u.User = url.User(scrubbedPart)
+		changed = true
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{scrubbedPart}
+		}
+		u.RawQuery = query.Encode()
+		changed = true
+	}
+
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if isPasskey(segment) {
+			segments[i] = scrubbedPart
+			changed = true
+		}
+	}
+
+	if !changed {
+		return raw
+	}
+
+	u.Path, u.RawPath = strings.Join(segments, "/"), ""
+
+	return u.String()
+}
+
+// scrubMagnet scrubs the url parameters of a magnet link, keeping the others
+// (e.g. the info hash) as written.
+func scrubMagnet(raw string) string {
+	params := strings.Split(strings.TrimPrefix(raw, "magnet:?"), "&")
+
+	for i, param := range params {
+		key, value := param, ""
+		if eq := strings.IndexByte(param, '='); eq >= 0 {
+			key, value = param[:eq], param[eq+1:]
+		}
+
+		// tr.1, tr.2... are numbered trackers
+		if dot := strings.IndexByte(key, '.'); dot >= 0 {
+			key = key[:dot]
+		}
+
+		if !magnetURLParams[key] {
+			continue
+		}
+
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			if scrubbedURL := ScrubURL(unescaped); scrubbedURL != unescaped {
+				params[i] = param[:len(param)-len(value)] + url.QueryEscape(scrubbedURL)
+			}
+		}
+	}
+
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// isPasskey reports whether a path segment looks like a per user key, i.e. a
+// long run of letters and digits.
+func isPasskey(segment string) bool {
+	if len(segment) < 16 {
+		return false
+	}
+
+	digits := false
+	for _, r := range segment {
+		switch {
+		case unicode.IsDigit(r):
+			digits = true
+		case r > unicode.MaxASCII || !(unicode.IsLetter(r) || r == '-' || r == '_'):
+			return false
+		}
+	}
+
+	return digits
+}
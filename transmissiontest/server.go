@@ -0,0 +1,864 @@
+// Package transmissiontest provides an in-memory Transmission daemon for
+// tests. It implements the rpc state machine (session-id handshake, basic
+// auth, torrents, queue and session) on top of net/http/httptest, with
+// optional fault injection.
+package transmissiontest
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/metainfo"
+)
+
+const (
+	DefaultSessionID   = "transmissiontest-session"
+	DefaultDownloadDir = "/downloads"
+	DefaultFreeSpace   = int64(1) << 40
+	DefaultVerifyPolls = 2
+
+	resultUnknownMethod = "method name not recognized"
+)
+
+// Fault alters the answer of the server for the given method (any method when
+// empty). Result replaces the rpc result, Status answers with an http status
+// code, Drop closes the connection without answering, and Delay is waited
+// before answering. Times limits how many requests are affected, 0 means all.
+type Fault struct {
+	Method transmission.Method
+	Result string
+	Status int
+	Drop   bool
+	Delay  time.Duration
+	Times  int
+}
+
+// Request is an rpc request received by the server, after the handshake.
+type Request struct {
+	Method    transmission.Method
+	Arguments json.RawMessage
+	Tag       int64
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	username  string
+	password  string
+	sessionID string
+	torrents  []*transmission.Torrent // in queue order
+	removed   []int64
+	idle      bool
+	nextID    int64
+	session   map[string]interface{}
+	stats     transmission.CumulativeStats
+	freeSpace int64
+	portOpen  bool
+	faults    []*Fault
+	requests  []Request
+	closed    bool
+
+	verifyPolls int
+	verifying   map[*transmission.Torrent]*verification
+}
+
+// verification is a check in progress, ended after polls torrent-get requests
+// by restoring the status the torrent had before.
+type verification struct {
+	previous int64
+	polls    int
+}
+
+type Option func(*Server)
+
+// WithCredentials makes the server require basic auth.
+func WithCredentials(username, password string) Option {
+	return func(s *Server) {
+		s.username = username
+		s.password = password
+	}
+}
+
+func WithSessionID(sessionID string) Option {
+	return func(s *Server) {
+		s.sessionID = sessionID
+	}
+}
+
+// WithSession sets the initial values returned by session-get.
+func WithSession(session transmission.Session) Option {
+	return func(s *Server) {
+		s.session = toMap(session)
+	}
+}
+
+func WithFreeSpace(bytes int64) Option {
+	return func(s *Server) {
+		s.freeSpace = bytes
+	}
+}
+
+func WithPortOpen(open bool) Option {
+	return func(s *Server) {
+		s.portOpen = open
+	}
+}
+
+// WithVerifyPolls sets how many torrent-get requests a verify lasts, reported
+// as StatusCheckWait then StatusCheck, DefaultVerifyPolls when not set. With 0
+// the check is over before the next request.
+func WithVerifyPolls(polls int) Option {
+	return func(s *Server) {
+		s.verifyPolls = polls
+	}
+}
+
+// NewServer starts a server, it must be closed with Close.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		sessionID:   DefaultSessionID,
+		nextID:      1,
+		freeSpace:   DefaultFreeSpace,
+		portOpen:    true,
+		verifyPolls: DefaultVerifyPolls,
+		verifying:   make(map[*transmission.Torrent]*verification),
+		session: toMap(transmission.Session{
+			DownloadDir:       DefaultDownloadDir,
+			PeerPort:          51413,
+			RPCVersion:        15,
+			RPCVersionMinimum: 1,
+			Version:           "2.94 (transmissiontest)",
+		}),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Client returns a client connected to the server, with its credentials.
+// The given options are applied afterwards.
+func (s *Server) Client(opts ...transmission.Option) *transmission.Client {
+	base := []transmission.Option{
+		transmission.WithURL(s.URL),
+		transmission.WithHTTPClient(s.Server.Client()),
+	}
+
+	if s.username != "" {
+		base = append(base, transmission.WithBasicAuth(s.username, s.password))
+	}
+
+	client, err := transmission.New(append(base, opts...)...)
+	if err != nil {
+		panic(fmt.Sprintf("transmissiontest: invalid client options: %v", err))
+	}
+
+	return client
+}
+
+// InjectFault registers a fault, faults are checked in registration order.
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := fault
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every registered fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// RotateSessionID changes the session id, as the daemon does on restart.
+func (s *Server) RotateSessionID(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessionID = sessionID
+}
+
+// SetIdle makes "recently-active" requests list no torrents, as the daemon
+// does when none had activity lately. By default every torrent is listed.
+func (s *Server) SetIdle(idle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idle = idle
+}
+
+// Restart simulates a daemon restart: the session id changes and the
+// torrent ids are reassigned from 1 following ids, given as torrent hashes
+// (the remaining torrents follow in queue order).
+func (s *Server) Restart(sessionID string, hashes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rank := make(map[string]int, len(hashes))
+	for i, hash := range hashes {
+		rank[strings.ToLower(hash)] = i - len(hashes)
+	}
+
+	byID := append([]*transmission.Torrent(nil), s.torrents...)
+	sort.SliceStable(byID, func(i, j int) bool {
+		return rank[strings.ToLower(byID[i].HashString)] < rank[strings.ToLower(byID[j].HashString)]
+	})
+
+	for i, t := range byID {
+		t.ID = int64(i + 1)
+	}
+
+	s.nextID = int64(len(byID) + 1)
+	s.removed = nil
+	s.sessionID = sessionID
+}
+
+// Requests returns the rpc requests received, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Closed reports whether a session-close request was received.
+func (s *Server) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// AddTorrent adds a torrent directly to the server state, filling the id,
+// queue position and download dir when empty. It returns the stored torrent.
+func (s *Server) AddTorrent(torrent transmission.Torrent) transmission.Torrent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return *s.addTorrent(torrent)
+}
+
+// Torrents returns a copy of the torrents, in queue order.
+func (s *Server) Torrents() []transmission.Torrent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	torrents := make([]transmission.Torrent, 0, len(s.torrents))
+	for _, t := range s.torrents {
+		torrents = append(torrents, *t)
+	}
+
+	return torrents
+}
+
+// UpdateTorrent changes the torrent with the given hash, e.g. to simulate
+// download progress. It reports whether the torrent exists.
+func (s *Server) UpdateTorrent(hash string, fn func(t *transmission.Torrent)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.torrents {
+		if strings.EqualFold(t.HashString, hash) {
+			fn(t)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Session returns the current session values.
+func (s *Server) Session() transmission.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var session transmission.Session
+	_ = fromMap(s.session, &session)
+
+	return session
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if s.username != "" {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != s.username || password != s.password {
+			w.Header().Set("Content-Type", "text/html; charset=ISO-8859-1")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("<h1>401: Unauthorized</h1>"))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	sessionID := s.sessionID
+	s.mu.Unlock()
+
+	if r.Header.Get(transmission.SessionIDHeader) != sessionID {
+		w.Header().Set(transmission.SessionIDHeader, sessionID)
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte("<h1>409: Conflict</h1>"))
+		return
+	}
+
+	var req struct {
+		Method    transmission.Method `json:"method"`
+		Arguments json.RawMessage     `json:"arguments"`
+		Tag       int64               `json:"tag"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: req.Method, Arguments: req.Arguments, Tag: req.Tag})
+	fault := s.matchFault(req.Method)
+	s.mu.Unlock()
+
+	if fault != nil {
+		time.Sleep(fault.Delay)
+
+		switch {
+		case fault.Drop:
+			if hj, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hj.Hijack(); err == nil {
+					_ = conn.Close()
+					return
+				}
+			}
+		case fault.Status != 0:
+			w.WriteHeader(fault.Status)
+			return
+		case fault.Result != "":
+			writeResponse(w, fault.Result, nil, req.Tag)
+			return
+		}
+	}
+
+	args := make(map[string]interface{})
+	if len(req.Arguments) > 0 {
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			writeResponse(w, "invalid arguments", nil, req.Tag)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	result, arguments := s.dispatch(req.Method, args)
+	s.mu.Unlock()
+
+	writeResponse(w, result, arguments, req.Tag)
+}
+
+// matchFault returns the first active fault for the method, consuming one of
+// its uses. It must be called with s.mu held.
+func (s *Server) matchFault(method transmission.Method) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != method {
+			continue
+		}
+
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+
+		return f
+	}
+
+	return nil
+}
+
+func writeResponse(w http.ResponseWriter, result string, arguments interface{}, tag int64) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"result":    result,
+		"arguments": arguments,
+		"tag":       tag,
+	})
+}
+
+// dispatch runs the method, it must be called with s.mu held.
+func (s *Server) dispatch(method transmission.Method, args map[string]interface{}) (string, interface{}) {
+	switch method {
+	case transmission.MethodTorrentAdd:
+		return s.torrentAdd(args)
+	case transmission.MethodTorrentGet:
+		return s.torrentGet(args)
+	case transmission.MethodTorrentSet:
+		return s.torrentSet(args)
+	case transmission.MethodTorrentRemove:
+		return s.torrentRemove(args)
+	case transmission.MethodTorrentMove:
+		for _, t := range s.selectTorrents(args["ids"]) {
+			if location, ok := args["location"].(string); ok {
+				t.DownloadDir = location
+			}
+		}
+		return transmission.ResponseResultSuccess, nil
+	case transmission.MethodTorrentRename:
+		return s.torrentRename(args)
+	case transmission.MethodTorrentStart, transmission.MethodTorrentStartNow:
+		for _, t := range s.selectTorrents(args["ids"]) {
+			t.Status = transmission.StatusDownload
+			if t.PercentDone >= 1 {
+				t.Status = transmission.StatusSeed
+			}
+		}
+		return transmission.ResponseResultSuccess, nil
+	case transmission.MethodTorrentStop:
+		for _, t := range s.selectTorrents(args["ids"]) {
+			t.Status = transmission.StatusStopped
+		}
+		return transmission.ResponseResultSuccess, nil
+	case transmission.MethodTorrentVerify:
+		for _, t := range s.selectTorrents(args["ids"]) {
+			s.verify(t)
+		}
+		return transmission.ResponseResultSuccess, nil
+	case transmission.MethodTorrentReannounce:
+		for _, t := range s.selectTorrents(args["ids"]) {
+			t.ManualAnnounceTime = time.Now().Unix()
+		}
+		return transmission.ResponseResultSuccess, nil
+	case transmission.MethodQueueMoveTop, transmission.MethodQueueMoveUp,
+		transmission.MethodQueueMoveDown, transmission.MethodQueueMoveBottom:
+		s.queueMove(method, s.selectTorrents(args["ids"]))
+		return transmission.ResponseResultSuccess, nil
+	case transmission.MethodSessionGet:
+		return transmission.ResponseResultSuccess, filterFields(s.session, args["fields"])
+	case transmission.MethodSessionSet:
+		for key, value := range args {
+			s.session[key] = value
+		}
+		return transmission.ResponseResultSuccess, nil
+	case transmission.MethodSessionStats:
+		return transmission.ResponseResultSuccess, s.sessionStats()
+	case transmission.MethodSessionClose:
+		s.closed = true
+		return transmission.ResponseResultSuccess, nil
+	case transmission.MethodFreeSpace:
+		path, _ := args["path"].(string)
+		return transmission.ResponseResultSuccess, transmission.FreeSpace{Path: path, SizeBytes: s.freeSpace}
+	case transmission.MethodPortTest:
+		return transmission.ResponseResultSuccess, transmission.PortCheck{PortIsOpen: s.portOpen}
+	case transmission.MethodBlockListUpdate:
+		size, _ := s.session["blocklist-size"].(float64)
+		return transmission.ResponseResultSuccess, transmission.BlockList{BlockListSize: int64(size)}
+	}
+
+	return resultUnknownMethod, nil
+}
+
+func (s *Server) addTorrent(torrent transmission.Torrent) *transmission.Torrent {
+	t := torrent
+	if t.ID == 0 {
+		t.ID = s.nextID
+	}
+
+	if t.ID >= s.nextID {
+		s.nextID = t.ID + 1
+	}
+
+	if t.DownloadDir == "" {
+		t.DownloadDir, _ = s.session["download-dir"].(string)
+	}
+
+	if t.AddedDate == 0 {
+		t.AddedDate = time.Now().Unix()
+	}
+
+	s.torrents = append(s.torrents, &t)
+	s.renumberQueue()
+
+	return &t
+}
+
+func (s *Server) torrentAdd(args map[string]interface{}) (string, interface{}) {
+	filename, _ := args["filename"].(string)
+	metainfo, _ := args["metainfo"].(string)
+
+	var hash, name string
+
+	switch {
+	case metainfo != "":
+		buf, err := base64.StdEncoding.DecodeString(metainfo)
+		if err != nil {
+			return "invalid or corrupt torrent file", nil
+		}
+		hash, name = metainfoIdentity(buf)
+	case strings.HasPrefix(filename, "magnet:"):
+		var ok bool
+		if hash, name, ok = magnetIdentity(filename); !ok {
+			return "invalid or corrupt torrent file", nil
+		}
+	case filename != "":
+		sum := sha1.Sum([]byte(filename))
+		hash = hex.EncodeToString(sum[:])
+	default:
+		return "no filename or metainfo specified", nil
+	}
+
+	for _, t := range s.torrents {
+		if t.HashString == hash {
+			return transmission.ResponseResultSuccess, map[string]interface{}{
+				"torrent-duplicate": identity(t),
+			}
+		}
+	}
+
+	if name == "" {
+		name = hash
+	}
+
+	status := transmission.StatusDownload
+	if paused, _ := args["paused"].(bool); paused {
+		status = transmission.StatusStopped
+	}
+
+	downloadDir, _ := args["download-dir"].(string)
+	t := s.addTorrent(transmission.Torrent{
+		HashString:  hash,
+		Name:        name,
+		Status:      status,
+		DownloadDir: downloadDir,
+		MagnetLink:  "magnet:?xt=urn:btih:" + hash + "&dn=" + url.QueryEscape(name),
+	})
+
+	return transmission.ResponseResultSuccess, map[string]interface{}{"torrent-added": identity(t)}
+}
+
+// verify queues the check of t, unless it is already checking.
+func (s *Server) verify(t *transmission.Torrent) {
+	if _, ok := s.verifying[t]; ok {
+		return
+	}
+
+	if s.verifyPolls == 0 {
+		return
+	}
+
+	s.verifying[t] = &verification{previous: t.Status}
+	t.Status, t.RecheckProgress = transmission.StatusCheckWait, 0
+}
+
+// advanceVerifications moves the checks in progress one step, after a
+// torrent-get request.
+func (s *Server) advanceVerifications() {
+	for t, v := range s.verifying {
+		v.polls++
+
+		if v.polls < s.verifyPolls {
+			t.Status = transmission.StatusCheck
+			t.RecheckProgress = float64(v.polls) / float64(s.verifyPolls)
+			continue
+		}
+
+		t.Status, t.RecheckProgress = v.previous, 0
+		delete(s.verifying, t)
+	}
+}
+
+func (s *Server) torrentGet(args map[string]interface{}) (string, interface{}) {
+	torrents := make([]interface{}, 0)
+
+	// the daemon lists torrents by id, not by queue position
+	selected := s.selectTorrents(args["ids"])
+	if args["ids"] == "recently-active" && s.idle {
+		selected = nil
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].ID < selected[j].ID })
+
+	for _, t := range selected {
+		torrents = append(torrents, filterFields(toMap(t), args["fields"]))
+	}
+
+	arguments := map[string]interface{}{"torrents": torrents}
+	if args["ids"] == "recently-active" {
+		// every torrent is considered active unless idle
+		arguments["removed"] = append([]int64{}, s.removed...)
+	}
+
+	s.advanceVerifications()
+
+	return transmission.ResponseResultSuccess, arguments
+}
+
+func (s *Server) torrentSet(args map[string]interface{}) (string, interface{}) {
+	torrents := s.selectTorrents(args["ids"])
+
+	for _, t := range torrents {
+		values := toMap(t)
+
+		for key, value := range args {
+			switch key {
+			case "ids", "files-wanted", "files-unwanted", "priority-high", "priority-low",
+				"priority-normal", "trackerAdd", "trackerRemove", "trackerReplace", "queuePosition":
+				continue
+			case "location":
+				values["downloadDir"] = value
+			default:
+				values[key] = value
+			}
+		}
+
+		_ = fromMap(values, t)
+	}
+
+	if position, ok := args["queuePosition"].(float64); ok && len(torrents) > 0 {
+		s.moveTo(torrents, int(position))
+	}
+
+	return transmission.ResponseResultSuccess, nil
+}
+
+func (s *Server) torrentRemove(args map[string]interface{}) (string, interface{}) {
+	removed := make(map[int64]bool)
+	for _, t := range s.selectTorrents(args["ids"]) {
+		removed[t.ID] = true
+		delete(s.verifying, t)
+		s.removed = append(s.removed, t.ID)
+	}
+
+	kept := s.torrents[:0]
+	for _, t := range s.torrents {
+		if !removed[t.ID] {
+			kept = append(kept, t)
+		}
+	}
+
+	s.torrents = kept
+	s.renumberQueue()
+
+	return transmission.ResponseResultSuccess, nil
+}
+
+func (s *Server) torrentRename(args map[string]interface{}) (string, interface{}) {
+	torrents := s.selectTorrents(args["ids"])
+	if len(torrents) != 1 {
+		return "torrent-rename-path requires 1 torrent", nil
+	}
+
+	path, _ := args["path"].(string)
+	name, _ := args["name"].(string)
+
+	t := torrents[0]
+	if path != t.Name || name == "" || strings.Contains(name, "/") {
+		return "Invalid argument", nil
+	}
+
+	t.Name = name
+
+	return transmission.ResponseResultSuccess, map[string]interface{}{"id": t.ID, "name": name, "path": path}
+}
+
+func (s *Server) sessionStats() transmission.SessionStats {
+	stats := transmission.SessionStats{
+		TorrentCount:    int64(len(s.torrents)),
+		CumulativeStats: s.stats,
+	}
+
+	for _, t := range s.torrents {
+		if t.Status == transmission.StatusStopped {
+			stats.PausedTorrentCount++
+		} else {
+			stats.ActiveTorrentCount++
+		}
+
+		stats.DownloadSpeed += t.RateDownload
+		stats.UploadSpeed += t.RateUpload
+	}
+
+	return stats
+}
+
+// selectTorrents resolves the "ids" argument: missing means all torrents,
+// otherwise a number, a hash, a list of both, or "recently-active".
+func (s *Server) selectTorrents(ids interface{}) []*transmission.Torrent {
+	if ids == nil || ids == "recently-active" {
+		return append([]*transmission.Torrent(nil), s.torrents...)
+	}
+
+	list, ok := ids.([]interface{})
+	if !ok {
+		list = []interface{}{ids}
+	}
+
+	var selected []*transmission.Torrent
+
+	for _, t := range s.torrents {
+		for _, id := range list {
+			switch v := id.(type) {
+			case float64:
+				if int64(v) == t.ID {
+					selected = append(selected, t)
+				}
+			case string:
+				if strings.EqualFold(v, t.HashString) {
+					selected = append(selected, t)
+				}
+			}
+		}
+	}
+
+	return selected
+}
+
+func (s *Server) queueMove(method transmission.Method, torrents []*transmission.Torrent) {
+	if len(torrents) == 0 {
+		return
+	}
+
+	switch method {
+	case transmission.MethodQueueMoveTop:
+		s.moveTo(torrents, 0)
+	case transmission.MethodQueueMoveBottom:
+		s.moveTo(torrents, len(s.torrents))
+	case transmission.MethodQueueMoveUp:
+		moved := queued(torrents)
+		for i := 1; i < len(s.torrents); i++ {
+			if moved[s.torrents[i].ID] && !moved[s.torrents[i-1].ID] {
+				s.torrents[i-1], s.torrents[i] = s.torrents[i], s.torrents[i-1]
+			}
+		}
+		s.renumberQueue()
+	case transmission.MethodQueueMoveDown:
+		moved := queued(torrents)
+		for i := len(s.torrents) - 2; i >= 0; i-- {
+			if moved[s.torrents[i].ID] && !moved[s.torrents[i+1].ID] {
+				s.torrents[i], s.torrents[i+1] = s.torrents[i+1], s.torrents[i]
+			}
+		}
+		s.renumberQueue()
+	}
+}
+
+func queued(torrents []*transmission.Torrent) map[int64]bool {
+	ids := make(map[int64]bool, len(torrents))
+	for _, t := range torrents {
+		ids[t.ID] = true
+	}
+
+	return ids
+}
+
+// moveTo places the torrents, keeping their relative order, at position.
+func (s *Server) moveTo(torrents []*transmission.Torrent, position int) {
+	moved := queued(torrents)
+
+	var rest []*transmission.Torrent
+	for _, t := range s.torrents {
+		if !moved[t.ID] {
+			rest = append(rest, t)
+		}
+	}
+
+	if position < 0 {
+		position = 0
+	}
+
+	if position > len(rest) {
+		position = len(rest)
+	}
+
+	queue := append([]*transmission.Torrent(nil), rest[:position]...)
+	queue = append(queue, torrents...)
+	s.torrents = append(queue, rest[position:]...)
+	s.renumberQueue()
+}
+
+func (s *Server) renumberQueue() {
+	for i, t := range s.torrents {
+		t.QueuePosition = int64(i)
+	}
+}
+
+func identity(t *transmission.Torrent) map[string]interface{} {
+	return map[string]interface{}{"id": t.ID, "name": t.Name, "hashString": t.HashString}
+}
+
+// metainfoIdentity returns the info hash and name of a .torrent, falling back
+// to the hash of the whole file when it can not be parsed.
+func metainfoIdentity(buf []byte) (string, string) {
+	if m, err := metainfo.Parse(buf); err == nil {
+		return m.HashString(), m.Name
+	}
+
+	sum := sha1.Sum(buf)
+	return hex.EncodeToString(sum[:]), ""
+}
+
+// magnetIdentity returns the hex info hash and display name of a magnet link.
+func magnetIdentity(link string) (string, string, bool) {
+	m, err := metainfo.ParseMagnet(link)
+	if err != nil {
+		return "", "", false
+	}
+
+	return m.HashString(), m.Name, true
+}
+
+func toMap(value interface{}) map[string]interface{} {
+	var m map[string]interface{}
+
+	buf, _ := json.Marshal(value)
+	_ = json.Unmarshal(buf, &m)
+
+	return m
+}
+
+func fromMap(m map[string]interface{}, target interface{}) error {
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(buf, target)
+}
+
+// filterFields returns a copy of values with the requested keys, or all of
+// them when no field list was given.
+func filterFields(values map[string]interface{}, fields interface{}) map[string]interface{} {
+	filtered := make(map[string]interface{}, len(values))
+
+	list, ok := fields.([]interface{})
+	if !ok || len(list) == 0 {
+		for key, value := range values {
+			filtered[key] = value
+		}
+
+		return filtered
+	}
+
+	for _, field := range list {
+		if name, ok := field.(string); ok {
+			if value, ok := values[name]; ok {
+				filtered[name] = value
+			}
+		}
+	}
+
+	return filtered
+}
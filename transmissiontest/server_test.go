@@ -0,0 +1,367 @@
+package transmissiontest
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/stretchr/testify/assert"
+)
+
+const magnet = "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=debian.iso"
+
+func names(torrents []transmission.Torrent) []string {
+	var list []string
+	for _, t := range torrents {
+		list = append(list, t.Name)
+	}
+
+	return list
+}
+
+func TestServer_Handshake(t *testing.T) {
+	t.Run("should require session id handshake", func(st *testing.T) {
+		s := NewServer(WithSessionID("abc"))
+		defer s.Close()
+
+		client := s.Client()
+		assert.NoError(st, client.SessionClose(context.Background()))
+		assert.Equal(st, "abc", client.SessionID)
+		assert.True(st, s.Closed())
+		assert.Len(st, s.Requests(), 1)
+	})
+
+	t.Run("should renegotiate rotated session ids", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		client := s.Client()
+		assert.NoError(st, client.Ping(context.Background()))
+
+		s.RotateSessionID("restarted")
+		_, err := client.SessionGet(context.Background())
+		assert.NoError(st, err)
+		assert.Equal(st, "restarted", client.SessionID)
+	})
+
+	t.Run("should answer pings after the handshake", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		down := NewServer()
+		down.Close()
+
+		client := s.Client(transmission.WithEndpoints(down.URL, s.URL))
+		_, err := client.SessionGet(context.Background())
+		assert.NoError(st, err)
+
+		assert.NoError(st, client.Ping(context.Background()))
+		assert.NoError(st, client.CheckEndpoints(context.Background()))
+		assert.Equal(st, s.URL+transmission.DefaultRPCPath, client.ActiveEndpoint())
+	})
+
+	t.Run("should require basic auth", func(st *testing.T) {
+		s := NewServer(WithCredentials("admin", "secret"))
+		defer s.Close()
+
+		assert.NoError(st, s.Client().SessionClose(context.Background()))
+
+		err := s.Client(transmission.WithBasicAuth("admin", "wrong")).SessionClose(context.Background())
+		assert.True(st, errors.Is(err, transmission.ErrNonJSONResponse))
+		assert.Contains(st, err.Error(), "status 401")
+	})
+}
+
+func TestServer_Torrents(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should add torrents and detect duplicates", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+		client := s.Client()
+
+		added, err := client.TorrentAdd(ctx, transmission.TorrentAdd{Filename: magnet, Paused: true})
+		assert.NoError(st, err)
+		assert.False(st, added.Duplicate)
+		assert.Equal(st, int64(1), added.ID)
+		assert.Equal(st, "debian.iso", added.Name)
+		assert.Equal(st, "0123456789abcdef0123456789abcdef01234567", added.HashString)
+
+		// same info hash, in base32
+		duplicate, err := client.TorrentAdd(ctx, transmission.TorrentAdd{
+			Filename: "magnet:?xt=urn:btih:AERUKZ4JVPG66AJDIVTYTK6N54ASGRLH",
+		})
+		assert.NoError(st, err)
+		assert.True(st, duplicate.Duplicate)
+		assert.Equal(st, added.ID, duplicate.ID)
+
+		metainfo := base64.StdEncoding.EncodeToString([]byte("d4:infod4:name3:abcee"))
+		fromFile, err := client.TorrentAdd(ctx, transmission.TorrentAdd{MetaInfo: metainfo, DownloadDir: "/data"})
+		assert.NoError(st, err)
+		assert.Equal(st, int64(2), fromFile.ID)
+
+		torrents := s.Torrents()
+		assert.Len(st, torrents, 2)
+		assert.Equal(st, int64(0), torrents[0].Status)
+		assert.Equal(st, DefaultDownloadDir, torrents[0].DownloadDir)
+		assert.Equal(st, int64(4), torrents[1].Status)
+		assert.Equal(st, "/data", torrents[1].DownloadDir)
+	})
+
+	t.Run("should identify added .torrent files by info hash", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		info := "d4:name3:abc12:piece lengthi1e6:pieces20:aaaaaaaaaaaaaaaaaaaae"
+		sum := sha1.Sum([]byte(info))
+
+		metainfo := base64.StdEncoding.EncodeToString([]byte("d4:info" + info + "e"))
+		added, err := s.Client().TorrentAdd(ctx, transmission.TorrentAdd{MetaInfo: metainfo})
+		assert.NoError(st, err)
+		assert.Equal(st, hex.EncodeToString(sum[:]), added.HashString)
+		assert.Equal(st, "abc", added.Name)
+	})
+
+	t.Run("should fail adding invalid torrents", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		_, err := s.Client().TorrentAdd(ctx, transmission.TorrentAdd{})
+		assert.Error(st, err)
+
+		_, err = s.Client().TorrentAdd(ctx, transmission.TorrentAdd{Filename: "magnet:?dn=nohash"})
+		assert.Error(st, err)
+	})
+
+	t.Run("should get torrents by id and hash with selected fields", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		s.AddTorrent(transmission.Torrent{Name: "one", HashString: "aaaa", TotalSize: 10})
+		s.AddTorrent(transmission.Torrent{Name: "two", HashString: "bbbb", TotalSize: 20})
+		s.AddTorrent(transmission.Torrent{Name: "three", HashString: "cccc", TotalSize: 30})
+
+		torrents, err := s.Client().TorrentGet(ctx, transmission.TorrentGet{
+			Ids:    []interface{}{1, "CCCC"},
+			Fields: []string{"id", "name"},
+		})
+		assert.NoError(st, err)
+		assert.Equal(st, []transmission.Torrent{{ID: 1, Name: "one"}, {ID: 3, Name: "three"}}, torrents)
+
+		torrents, err = s.Client().TorrentGet(ctx, transmission.TorrentGet{})
+		assert.NoError(st, err)
+		assert.Len(st, torrents, 3)
+		assert.Equal(st, int64(20), torrents[1].TotalSize)
+	})
+
+	t.Run("should list no recently active torrents when idle", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		s.AddTorrent(transmission.Torrent{Name: "one", HashString: "aaaa"})
+
+		torrents, err := s.Client().TorrentGet(ctx, transmission.TorrentGet{Ids: "recently-active"})
+		assert.NoError(st, err)
+		assert.Len(st, torrents, 1)
+
+		s.SetIdle(true)
+		torrents, err = s.Client().TorrentGet(ctx, transmission.TorrentGet{Ids: "recently-active"})
+		assert.NoError(st, err)
+		assert.Empty(st, torrents)
+	})
+
+	t.Run("should start, stop, set, move, rename and remove torrents", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+		client := s.Client()
+
+		s.AddTorrent(transmission.Torrent{Name: "one", HashString: "aaaa"})
+		s.AddTorrent(transmission.Torrent{Name: "two", HashString: "bbbb", PercentDone: 1})
+		all := transmission.Filter{Ids: []int64{1, 2}}
+
+		assert.NoError(st, client.TorrentStart(ctx, all))
+		assert.Equal(st, []int64{4, 6}, []int64{s.Torrents()[0].Status, s.Torrents()[1].Status})
+
+		assert.NoError(st, client.TorrentStop(ctx, transmission.Filter{Ids: []string{"aaaa"}}))
+		assert.Equal(st, int64(0), s.Torrents()[0].Status)
+
+		assert.NoError(st, client.TorrentSet(ctx, transmission.TorrentSet{
+			Ids:           []int64{2},
+			Labels:        []string{"linux"},
+			DownloadLimit: 100,
+			QueuePosition: 1,
+		}))
+		assert.Equal(st, []string{"linux"}, s.Torrents()[1].Labels)
+		assert.Equal(st, int64(100), s.Torrents()[1].DownloadLimit)
+
+		assert.NoError(st, client.TorrentMove(ctx, transmission.TorrentMove{Ids: []int64{1}, Location: "/moved"}))
+		assert.Equal(st, "/moved", s.Torrents()[0].DownloadDir)
+
+		renamed, err := client.TorrentRename(ctx, transmission.TorrentRename{Ids: []int64{1}, Path: "one", Name: "uno"})
+		assert.NoError(st, err)
+		assert.Equal(st, "uno", renamed.Name)
+		assert.Equal(st, "uno", s.Torrents()[0].Name)
+
+		_, err = client.TorrentRename(ctx, transmission.TorrentRename{Ids: []int64{1}, Path: "one", Name: "dos"})
+		assert.Error(st, err)
+
+		assert.NoError(st, client.TorrentRemove(ctx, transmission.TorrentRemove{Ids: []int64{1}}))
+		assert.Equal(st, []string{"two"}, names(s.Torrents()))
+		assert.Equal(st, int64(0), s.Torrents()[0].QueuePosition)
+	})
+
+	t.Run("should keep queue order", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+		client := s.Client()
+
+		for _, name := range []string{"a", "b", "c", "d"} {
+			s.AddTorrent(transmission.Torrent{Name: name, HashString: name})
+		}
+
+		assert.NoError(st, client.QueueMoveTop(ctx, transmission.Filter{Ids: []int64{3}}))
+		assert.Equal(st, []string{"c", "a", "b", "d"}, names(s.Torrents()))
+
+		assert.NoError(st, client.QueueMoveBottom(ctx, transmission.Filter{Ids: []int64{1}}))
+		assert.Equal(st, []string{"c", "b", "d", "a"}, names(s.Torrents()))
+
+		assert.NoError(st, client.QueueMoveUp(ctx, transmission.Filter{Ids: []int64{4}}))
+		assert.Equal(st, []string{"c", "d", "b", "a"}, names(s.Torrents()))
+
+		assert.NoError(st, client.QueueMoveDown(ctx, transmission.Filter{Ids: []int64{3, 4}}))
+		assert.Equal(st, []string{"b", "c", "d", "a"}, names(s.Torrents()))
+
+		// moving the first torrent up keeps it in place
+		assert.NoError(st, client.QueueMoveUp(ctx, transmission.Filter{Ids: []int64{2}}))
+		assert.Equal(st, []string{"b", "c", "d", "a"}, names(s.Torrents()))
+
+		torrents, _ := client.TorrentGet(ctx, transmission.TorrentGet{Fields: []string{"name", "queuePosition"}})
+		assert.Equal(st, int64(3), torrents[0].QueuePosition)
+	})
+}
+
+func TestServer_Session(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should get and set session values", func(st *testing.T) {
+		s := NewServer(WithSession(transmission.Session{DownloadDir: "/data", Version: "3.00"}))
+		defer s.Close()
+		client := s.Client()
+
+		session, err := client.SessionGet(ctx)
+		assert.NoError(st, err)
+		assert.Equal(st, "/data", session.DownloadDir)
+
+		assert.NoError(st, client.SessionSet(ctx, transmission.SessionSet{AltSpeedEnabled: true, PeerPort: 1234}))
+		assert.True(st, s.Session().AltSpeedEnabled)
+		assert.Equal(st, int64(1234), s.Session().PeerPort)
+		assert.Equal(st, "3.00", s.Session().Version)
+	})
+
+	t.Run("should compute session stats", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		s.AddTorrent(transmission.Torrent{HashString: "a", Status: 4, RateDownload: 10})
+		s.AddTorrent(transmission.Torrent{HashString: "b", Status: 6, RateUpload: 5})
+		s.AddTorrent(transmission.Torrent{HashString: "c"})
+
+		stats, err := s.Client().SessionStats(ctx)
+		assert.NoError(st, err)
+		assert.Equal(st, int64(3), stats.TorrentCount)
+		assert.Equal(st, int64(2), stats.ActiveTorrentCount)
+		assert.Equal(st, int64(1), stats.PausedTorrentCount)
+		assert.Equal(st, int64(10), stats.DownloadSpeed)
+		assert.Equal(st, int64(5), stats.UploadSpeed)
+	})
+
+	t.Run("should answer utility methods", func(st *testing.T) {
+		s := NewServer(WithFreeSpace(42), WithPortOpen(false))
+		defer s.Close()
+		client := s.Client()
+
+		free, err := client.FreeSpace(ctx, transmission.FreeSpace{Path: "/data"})
+		assert.NoError(st, err)
+		assert.Equal(st, transmission.FreeSpace{Path: "/data", SizeBytes: 42}, free)
+
+		port, err := client.PortCheck(ctx)
+		assert.NoError(st, err)
+		assert.False(st, port.PortIsOpen)
+
+		_, err = client.BlockListUpdate(ctx)
+		assert.NoError(st, err)
+	})
+}
+
+func TestServer_Faults(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should return injected rpc results", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		s.InjectFault(Fault{Method: transmission.MethodSessionGet, Result: "daemon busy", Times: 1})
+
+		_, err := s.Client().SessionGet(ctx)
+		assert.EqualError(st, err, "unexpected result: daemon busy")
+
+		_, err = s.Client().SessionGet(ctx)
+		assert.NoError(st, err)
+	})
+
+	t.Run("should answer injected http status", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		s.InjectFault(Fault{Status: http.StatusBadGateway})
+		err := s.Client().SessionClose(ctx)
+		assert.True(st, errors.Is(err, transmission.ErrNonJSONResponse))
+
+		s.ClearFaults()
+		assert.NoError(st, s.Client().SessionClose(ctx))
+	})
+
+	t.Run("should drop connections", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		s.InjectFault(Fault{Method: transmission.MethodTorrentGet, Drop: true})
+		_, err := s.Client().TorrentGet(ctx, transmission.TorrentGet{})
+		assert.Error(st, err)
+	})
+
+	t.Run("should delay answers", func(st *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		s.InjectFault(Fault{Delay: 50 * time.Millisecond})
+
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		client := s.Client()
+		_ = client.Ping(ctx)
+		assert.Error(st, client.SessionClose(timeout))
+	})
+
+	t.Run("should answer unknown methods", func(st *testing.T) {
+		s := NewServer(WithSessionID(""))
+		defer s.Close()
+
+		resp, err := http.Post(s.URL, "application/json", strings.NewReader(`{"method": "unknown"}`))
+		assert.NoError(st, err)
+		defer resp.Body.Close()
+
+		var body map[string]interface{}
+		assert.NoError(st, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(st, resultUnknownMethod, body["result"])
+	})
+}
@@ -0,0 +1,135 @@
+package transmission
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultWaitFields are the torrent fields fetched by the wait helpers, and
+// available to WaitUntil predicates.
+var DefaultWaitFields = []string{
+	"id", "hashString", "name", "status", "error", "errorString", "percentDone",
+	"leftUntilDone", "recheckProgress", "metadataPercentComplete", "downloadDir",
+}
+
+type waitOptions struct {
+	interval time.Duration
+	fields   []string
+	progress func(Torrent)
+}
+
+type WaitOption func(*waitOptions)
+
+// WithWaitInterval sets the delay between polls, DefaultPollInterval when
+// not set.
+func WithWaitInterval(interval time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.interval = interval
+	}
+}
+
+// WithWaitFields fetches fields besides DefaultWaitFields, for predicates
+// and progress callbacks needing them.
+func WithWaitFields(fields ...string) WaitOption {
+	return func(o *waitOptions) {
+		o.fields = append(o.fields, fields...)
+	}
+}
+
+// WithProgress sets a function called with the torrent after every poll,
+// e.g. to report RecheckProgress or PercentDone.
+func WithProgress(progress func(Torrent)) WaitOption {
+	return func(o *waitOptions) {
+		o.progress = progress
+	}
+}
+
+// WaitUntil polls the torrent identified by id (its id or hash) until
+// predicate returns true, and returns its last state. It returns
+// ErrTorrentNotFound when the torrent is removed meanwhile, and ctx.Err() when
+// ctx is done first.
+func (c *Client) WaitUntil(ctx context.Context, id interface{}, predicate func(Torrent) bool, opts ...WaitOption) (Torrent, error) {
+	options := waitOptions{fields: append([]string(nil), DefaultWaitFields...)}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	var torrent Torrent
+
+	err := poll(ctx, options.interval, func(ctx context.Context) (bool, error) {
+		torrents, err := c.TorrentGet(ctx, TorrentGet{Ids: []interface{}{id}, Fields: options.fields})
+		if err != nil {
+			return false, err
+		}
+
+		if len(torrents) == 0 {
+			return false, fmt.Errorf("%w: %v", ErrTorrentNotFound, id)
+		}
+
+		torrent = torrents[0]
+		if options.progress != nil {
+			options.progress(torrent)
+		}
+
+		return predicate(torrent), nil
+	})
+
+	return torrent, err
+}
+
+// WaitForStatus waits until the torrent has one of statuses.
+func (c *Client) WaitForStatus(ctx context.Context, id interface{}, statuses []int64, opts ...WaitOption) (Torrent, error) {
+	return c.WaitUntil(ctx, id, func(t Torrent) bool {
+		for _, status := range statuses {
+			if t.Status == status {
+				return true
+			}
+		}
+
+		return false
+	}, opts...)
+}
+
+// WaitForMetadata waits until the metadata of a torrent added from a magnet
+// link is downloaded.
+func (c *Client) WaitForMetadata(ctx context.Context, id interface{}, opts ...WaitOption) (Torrent, error) {
+	return c.WaitUntil(ctx, id, func(t Torrent) bool {
+		return t.MetadataPercentComplete >= 1
+	}, opts...)
+}
+
+// WaitForVerifyComplete verifies the torrent and waits until the check is
+// over. The torrent is fetched before the verify is sent, so a missing
+// torrent fails with ErrTorrentNotFound. The daemon queues the check before
+// answering the next request: any later poll reporting neither
+// StatusCheckWait nor StatusCheck follows the check, even one finished before
+// the first poll.
+func (c *Client) WaitForVerifyComplete(ctx context.Context, id interface{}, opts ...WaitOption) (Torrent, error) {
+	ids := []interface{}{id}
+
+	baseline, err := c.TorrentGet(ctx, TorrentGet{Ids: ids, Fields: []string{"id"}})
+	if err != nil {
+		return Torrent{}, err
+	}
+
+	if len(baseline) == 0 {
+		return Torrent{}, fmt.Errorf("%w: %v", ErrTorrentNotFound, id)
+	}
+
+	if err := c.TorrentVerify(ctx, Filter{Ids: ids}); err != nil {
+		return Torrent{}, err
+	}
+
+	return c.WaitUntil(ctx, id, func(t Torrent) bool {
+		return t.Status != StatusCheckWait && t.Status != StatusCheck
+	}, opts...)
+}
+
+// WaitForCompletion waits until all the wanted files of the torrent are
+// downloaded.
+func (c *Client) WaitForCompletion(ctx context.Context, id interface{}, opts ...WaitOption) (Torrent, error) {
+	return c.WaitUntil(ctx, id, func(t Torrent) bool {
+		return t.PercentDone >= 1 && t.LeftUntilDone == 0
+	}, opts...)
+}
@@ -0,0 +1,121 @@
+package transmission_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Wait(t *testing.T) {
+	ctx := context.Background()
+	interval := transmission.WithWaitInterval(time.Millisecond)
+
+	s := transmissiontest.NewServer()
+	defer s.Close()
+
+	s.AddTorrent(transmission.Torrent{Name: "debian.iso", HashString: "aaaa", Status: transmission.StatusDownload})
+	client := s.Client()
+
+	t.Run("should wait for completion reporting progress", func(st *testing.T) {
+		var reported []float64
+
+		torrent, err := client.WaitForCompletion(ctx, "aaaa", interval, transmission.WithProgress(func(t transmission.Torrent) {
+			reported = append(reported, t.PercentDone)
+
+			// the daemon downloads a quarter between polls
+			s.UpdateTorrent("aaaa", func(t *transmission.Torrent) {
+				t.PercentDone += 0.25
+				if t.PercentDone >= 1 {
+					t.Status = transmission.StatusSeed
+				}
+			})
+		}))
+
+		assert.NoError(st, err)
+		assert.Equal(st, []float64{0, 0.25, 0.5, 0.75, 1}, reported)
+		assert.Equal(st, transmission.StatusSeed, torrent.Status)
+	})
+
+	t.Run("should wait for a status", func(st *testing.T) {
+		assert.NoError(st, client.TorrentStop(ctx, transmission.Filter{Ids: []string{"aaaa"}}))
+
+		torrent, err := client.WaitForStatus(ctx, int64(1), []int64{transmission.StatusStopped}, interval)
+		assert.NoError(st, err)
+		assert.Equal(st, "aaaa", torrent.HashString)
+	})
+
+	t.Run("should verify and wait for the check", func(st *testing.T) {
+		var statuses []int64
+
+		torrent, err := client.WaitForVerifyComplete(ctx, "aaaa", interval, transmission.WithProgress(func(t transmission.Torrent) {
+			statuses = append(statuses, t.Status)
+		}))
+		assert.NoError(st, err)
+		assert.Equal(st, []int64{transmission.StatusCheckWait, transmission.StatusCheck, transmission.StatusStopped}, statuses)
+		assert.Equal(st, transmission.StatusStopped, torrent.Status)
+	})
+
+	t.Run("should wait for metadata", func(st *testing.T) {
+		s.UpdateTorrent("aaaa", func(t *transmission.Torrent) { t.MetadataPercentComplete = 1 })
+		_, err := client.WaitForMetadata(ctx, "aaaa", interval)
+		assert.NoError(st, err)
+	})
+
+	t.Run("should fetch extra fields for predicates", func(st *testing.T) {
+		s.UpdateTorrent("aaaa", func(t *transmission.Torrent) { t.Labels = []string{"done"} })
+
+		torrent, err := client.WaitUntil(ctx, "aaaa", func(t transmission.Torrent) bool {
+			return len(t.Labels) > 0
+		}, interval, transmission.WithWaitFields("labels"))
+		assert.NoError(st, err)
+		assert.Equal(st, []string{"done"}, torrent.Labels)
+	})
+
+	t.Run("should stop when the context is done", func(st *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		_, err := client.WaitForStatus(ctx, "aaaa", []int64{transmission.StatusSeedWait}, interval)
+		assert.True(st, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("should fail when the torrent is removed", func(st *testing.T) {
+		_, err := client.WaitForCompletion(ctx, "ffff", interval)
+		assert.True(st, errors.Is(err, transmission.ErrTorrentNotFound))
+
+		_, err = client.WaitForVerifyComplete(ctx, "ffff", interval)
+		assert.True(st, errors.Is(err, transmission.ErrTorrentNotFound))
+	})
+}
+
+func TestClient_WaitForVerifyComplete(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	t.Run("should see a check over before the first poll", func(st *testing.T) {
+		s := transmissiontest.NewServer(transmissiontest.WithVerifyPolls(0))
+		defer s.Close()
+
+		s.AddTorrent(transmission.Torrent{HashString: "aaaa", Status: transmission.StatusSeed})
+
+		polls := 0
+		torrent, err := s.Client().WaitForVerifyComplete(ctx, "aaaa", transmission.WithWaitInterval(time.Millisecond),
+			transmission.WithProgress(func(t transmission.Torrent) { polls++ }))
+		assert.NoError(st, err)
+		assert.Equal(st, 1, polls)
+		assert.Equal(st, transmission.StatusSeed, torrent.Status)
+
+		methods := make([]transmission.Method, 0)
+		for _, r := range s.Requests() {
+			methods = append(methods, r.Method)
+		}
+		assert.Equal(st, []transmission.Method{
+			transmission.MethodTorrentGet, transmission.MethodTorrentVerify, transmission.MethodTorrentGet,
+		}, methods)
+	})
+}
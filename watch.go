@@ -0,0 +1,454 @@
+package transmission
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultWatchFullSync = 10
+)
+
+// WatchFields are the torrent fields fetched by Watch, WatchOptions.Fields
+// adds to them.
+var WatchFields = []string{
+	"id", "hashString", "name", "status", "error", "errorString", "percentDone",
+	"leftUntilDone", "metadataPercentComplete", "labels", "rateDownload",
+	"rateUpload", "trackerStats",
+}
+
+type EventType string
+
+const (
+	EventAdded            EventType = "added"
+	EventRemoved          EventType = "removed"
+	EventMetadataReceived EventType = "metadata-received"
+	EventStarted          EventType = "started"
+	EventStopped          EventType = "stopped"
+	EventCompleted        EventType = "completed"
+	EventVerified         EventType = "verified"
+	EventErrorRaised      EventType = "error-raised"
+	EventErrorCleared     EventType = "error-cleared"
+	EventLabelsChanged    EventType = "labels-changed"
+	EventTrackerError     EventType = "tracker-error"
+	EventDownloadAbove    EventType = "download-above"
+	EventDownloadBelow    EventType = "download-below"
+	EventUploadAbove      EventType = "upload-above"
+	EventUploadBelow      EventType = "upload-below"
+	// EventSessionChanged reports a session setting change, see
+	// WatchOptions.Session.
+	EventSessionChanged EventType = "session-changed"
+	// EventWatchError reports a failed poll, the watch goes on.
+	EventWatchError EventType = "watch-error"
+)
+
+// Event is a change noticed between two snapshots. Torrent is the current
+// state (the last known one for EventRemoved) and Previous the state before
+// the change, empty for EventAdded.
+type Event struct {
+	Type     EventType
+	Torrent  Torrent
+	Previous Torrent
+	// Tracker is the failing tracker of EventTrackerError.
+	Tracker *TrackerStat
+	// Change is the session setting of EventSessionChanged.
+	Change *SessionChange
+	// Err is the poll error of EventWatchError.
+	Err error
+}
+
+// SessionChange is a session field which changed between two polls. Field is
+// its json name, e.g. "alt-speed-enabled", and Old and New its values.
+type SessionChange struct {
+	Field   string
+	Old     interface{}
+	New     interface{}
+	Session Session
+}
+
+// WatchOptions configures Watch. The zero value polls every
+// DefaultPollInterval without speed thresholds.
+type WatchOptions struct {
+	Interval time.Duration
+	// Fields are fetched besides WatchFields.
+	Fields []string
+	// FullSync is the number of polls between two complete listings, the
+	// others only fetch the recently active torrents. DefaultWatchFullSync
+	// when 0, 1 always lists all torrents.
+	FullSync int
+	// DownloadThreshold and UploadThreshold, in bytes per second, enable
+	// the speed events when positive.
+	DownloadThreshold int64
+	UploadThreshold   int64
+	// Buffer is the capacity of the event channel.
+	Buffer int
+	// Initial emits EventAdded for the torrents of the first snapshot, which
+	// otherwise only sets the baseline.
+	Initial bool
+	// Session also polls the session settings, reporting their changes
+	// with EventSessionChanged.
+	Session bool
+}
+
+// Watch polls the torrents and sends the changes between successive
+// snapshots on the returned channel, which is closed when ctx is done.
+// Torrents are identified by hash, so daemon restarts renumbering the ids do
+// not produce spurious events.
+func (c *Client) Watch(ctx context.Context, opts WatchOptions) <-chan Event {
+	return c.watch(ctx, opts, true)
+}
+
+// WatchSession polls the session settings only, sending EventSessionChanged
+// for every field changed between two polls. The first poll sets the
+// baseline.
+func (c *Client) WatchSession(ctx context.Context, opts WatchOptions) <-chan Event {
+	opts.Session = true
+	return c.watch(ctx, opts, false)
+}
+
+func (c *Client) watch(ctx context.Context, opts WatchOptions, torrents bool) <-chan Event {
+	events := make(chan Event, opts.Buffer)
+
+	w := watcher{
+		client:        c,
+		opts:          opts,
+		fields:        append(append([]string(nil), WatchFields...), opts.Fields...),
+		torrents:      make(map[string]Torrent),
+		watchTorrents: torrents,
+		events:        events,
+	}
+
+	if w.opts.FullSync <= 0 {
+		w.opts.FullSync = DefaultWatchFullSync
+	}
+
+	go func() {
+		defer close(events)
+		_ = poll(ctx, opts.Interval, w.poll)
+	}()
+
+	return events
+}
+
+type watcher struct {
+	client        *Client
+	opts          WatchOptions
+	fields        []string
+	events        chan<- Event
+	watchTorrents bool
+	torrents      map[string]Torrent
+	synced        bool
+	polls         int
+	session       *Session
+	sessionID     string
+}
+
+func (w *watcher) emit(ctx context.Context, event Event) bool {
+	select {
+	case w.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (w *watcher) poll(ctx context.Context) (bool, error) {
+	if w.watchTorrents {
+		if stop := w.pollTorrents(ctx); stop {
+			return true, nil
+		}
+	}
+
+	if w.opts.Session {
+		return w.pollSession(ctx), nil
+	}
+
+	return false, nil
+}
+
+// pollSession diffs the session with the previous one, it returns true when
+// ctx is done.
+func (w *watcher) pollSession(ctx context.Context) bool {
+	session, err := w.client.SessionGet(ctx)
+	if err != nil {
+		return ctx.Err() != nil || !w.emit(ctx, Event{Type: EventWatchError, Err: err})
+	}
+
+	previous := w.session
+	w.session = &session
+
+	if previous == nil {
+		return false
+	}
+
+	for _, change := range diffSession(*previous, session) {
+		change := change
+		if !w.emit(ctx, Event{Type: EventSessionChanged, Change: &change}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func diffSession(previous, session Session) []SessionChange {
+	var changes []SessionChange
+
+	old, current := reflect.ValueOf(previous), reflect.ValueOf(session)
+	for i := 0; i < current.NumField(); i++ {
+		a, b := old.Field(i).Interface(), current.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+
+		changes = append(changes, SessionChange{
+			Field:   jsonName(current.Type().Field(i)),
+			Old:     a,
+			New:     b,
+			Session: session,
+		})
+	}
+
+	return changes
+}
+
+// pollTorrents diffs the torrents with the previous snapshot, it returns true
+// when ctx is done.
+func (w *watcher) pollTorrents(ctx context.Context) bool {
+	full := !w.synced || w.polls%w.opts.FullSync == 0
+	w.polls++
+
+	torrents, removed, err := w.client.recentlyActive(ctx, full, w.fields)
+	if err == nil && w.restarted() && !full {
+		// the ids were reassigned, the recently active torrents alone do
+		// not tell which ones are known
+		full = true
+		w.polls = 1
+		torrents, removed, err = w.client.recentlyActive(ctx, full, w.fields)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return true
+		}
+
+		// the daemon may come back renumbered, resync from scratch
+		w.polls = 0
+
+		return !w.emit(ctx, Event{Type: EventWatchError, Err: err})
+	}
+
+	initial := !w.synced
+	w.synced = true
+
+	var events []Event
+
+	current := make(map[string]Torrent, len(torrents))
+	for _, t := range torrents {
+		current[strings.ToLower(t.HashString)] = t
+	}
+
+	if full {
+		var gone []Torrent
+		for hash, previous := range w.torrents {
+			if _, ok := current[hash]; !ok {
+				gone = append(gone, previous)
+				delete(w.torrents, hash)
+			}
+		}
+
+		sort.Slice(gone, func(i, j int) bool { return gone[i].ID < gone[j].ID })
+		for _, previous := range gone {
+			events = append(events, Event{Type: EventRemoved, Torrent: previous, Previous: previous})
+		}
+	} else if w.renumbered(current) {
+		// the daemon restarted, removed ids can not be trusted until the
+		// next poll lists everything
+		w.polls = 0
+	} else {
+		w.removeIds(removed, &events)
+	}
+
+	for _, t := range torrents {
+		hash := strings.ToLower(t.HashString)
+		previous, known := w.torrents[hash]
+		w.torrents[hash] = t
+
+		switch {
+		case !known && (!initial || w.opts.Initial):
+			events = append(events, Event{Type: EventAdded, Torrent: t})
+		case known:
+			events = append(events, w.diff(previous, t)...)
+		}
+	}
+
+	for _, event := range events {
+		if !w.emit(ctx, event) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// restarted records the session id of the daemon, reporting whether it
+// changed since the previous poll, i.e. the daemon restarted.
+func (w *watcher) restarted() bool {
+	w.client.mu.Lock()
+	sessionID := w.client.SessionID
+	w.client.mu.Unlock()
+
+	changed := w.sessionID != "" && sessionID != w.sessionID
+	w.sessionID = sessionID
+
+	return changed
+}
+
+// renumbered reports whether a torrent came with the id of another known
+// torrent.
+func (w *watcher) renumbered(current map[string]Torrent) bool {
+	byID := make(map[int64]string, len(w.torrents))
+	for hash, t := range w.torrents {
+		byID[t.ID] = hash
+	}
+
+	for hash, t := range current {
+		if known, ok := byID[t.ID]; ok && known != hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *watcher) removeIds(removed []int64, events *[]Event) {
+	byID := make(map[int64]string, len(w.torrents))
+	for hash, t := range w.torrents {
+		byID[t.ID] = hash
+	}
+
+	for _, id := range removed {
+		if hash, ok := byID[id]; ok {
+			*events = append(*events, Event{Type: EventRemoved, Torrent: w.torrents[hash], Previous: w.torrents[hash]})
+			delete(w.torrents, hash)
+		}
+	}
+}
+
+func (w *watcher) diff(previous, t Torrent) []Event {
+	var events []Event
+
+	add := func(eventType EventType) {
+		events = append(events, Event{Type: eventType, Torrent: t, Previous: previous})
+	}
+
+	if previous.MetadataPercentComplete < 1 && t.MetadataPercentComplete >= 1 {
+		add(EventMetadataReceived)
+	}
+
+	checking := func(status int64) bool { return status == StatusCheckWait || status == StatusCheck }
+	switch {
+	case checking(previous.Status) && !checking(t.Status):
+		add(EventVerified)
+	case previous.Status == StatusStopped && t.Status != StatusStopped && !checking(t.Status):
+		add(EventStarted)
+	case previous.Status != StatusStopped && t.Status == StatusStopped:
+		add(EventStopped)
+	}
+
+	if previous.PercentDone < 1 && t.PercentDone >= 1 {
+		add(EventCompleted)
+	}
+
+	switch {
+	case previous.Error == 0 && t.Error != 0:
+		add(EventErrorRaised)
+	case previous.Error != 0 && t.Error == 0:
+		add(EventErrorCleared)
+	}
+
+	if strings.Join(previous.Labels, "\x00") != strings.Join(t.Labels, "\x00") {
+		add(EventLabelsChanged)
+	}
+
+	for i := range t.TrackerStats {
+		tracker := t.TrackerStats[i]
+		if !tracker.HasAnnounced || tracker.LastAnnounceSucceeded {
+			continue
+		}
+
+		if before, ok := findTracker(previous.TrackerStats, tracker.ID); ok &&
+			!before.LastAnnounceSucceeded && before.LastAnnounceResult == tracker.LastAnnounceResult {
+			continue
+		}
+
+		events = append(events, Event{Type: EventTrackerError, Torrent: t, Previous: previous, Tracker: &tracker})
+	}
+
+	if threshold := w.opts.DownloadThreshold; threshold > 0 {
+		switch {
+		case previous.RateDownload < threshold && t.RateDownload >= threshold:
+			add(EventDownloadAbove)
+		case previous.RateDownload >= threshold && t.RateDownload < threshold:
+			add(EventDownloadBelow)
+		}
+	}
+
+	if threshold := w.opts.UploadThreshold; threshold > 0 {
+		switch {
+		case previous.RateUpload < threshold && t.RateUpload >= threshold:
+			add(EventUploadAbove)
+		case previous.RateUpload >= threshold && t.RateUpload < threshold:
+			add(EventUploadBelow)
+		}
+	}
+
+	return events
+}
+
+func findTracker(trackers []TrackerStat, id int64) (TrackerStat, bool) {
+	for _, tracker := range trackers {
+		if tracker.ID == id {
+			return tracker, true
+		}
+	}
+
+	return TrackerStat{}, false
+}
+
+// recentlyActive lists all the torrents when full is set, otherwise the
+// recently active ones and the ids of the recently removed ones.
+func (c *Client) recentlyActive(ctx context.Context, full bool, fields []string) ([]Torrent, []int64, error) {
+	if full {
+		torrents, err := c.TorrentGet(ctx, TorrentGet{Fields: fields})
+		return torrents, nil, err
+	}
+
+	resp, err := c.fetch(ctx, request{Method: MethodTorrentGet, Arguments: TorrentGet{Ids: "recently-active", Fields: fields}})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var torrents []Torrent
+	var removed []int64
+
+	if err := c.checkArguments(MethodTorrentGet, resp.Arguments); err != nil {
+		return nil, nil, err
+	}
+
+	if list, ok := resp.Arguments["torrents"]; ok {
+		if err := c.decode(MethodTorrentGet, list, &torrents); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if list, ok := resp.Arguments["removed"]; ok {
+		if err := c.decode(MethodTorrentGet, list, &removed); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return torrents, removed, nil
+}
@@ -0,0 +1,235 @@
+package transmission_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mfuentesg/transmission"
+	"github.com/mfuentesg/transmission/transmissiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func nextEvent(t *testing.T, events <-chan transmission.Event) transmission.Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return transmission.Event{}
+	}
+}
+
+func assertNoEvent(t *testing.T, events <-chan transmission.Event) {
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %s for %s", event.Type, event.Torrent.HashString)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_Watch(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+
+	s.AddTorrent(transmission.Torrent{Name: "debian.iso", HashString: "aaaa"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := s.Client()
+	events := client.Watch(ctx, transmission.WatchOptions{
+		Interval:          time.Millisecond,
+		FullSync:          3,
+		DownloadThreshold: 1000,
+	})
+
+	t.Run("should set the baseline silently", func(st *testing.T) {
+		assertNoEvent(st, events)
+	})
+
+	t.Run("should report added torrents", func(st *testing.T) {
+		s.AddTorrent(transmission.Torrent{Name: "ubuntu.iso", HashString: "bbbb", Status: transmission.StatusDownload})
+
+		event := nextEvent(st, events)
+		assert.Equal(st, transmission.EventAdded, event.Type)
+		assert.Equal(st, "ubuntu.iso", event.Torrent.Name)
+	})
+
+	t.Run("should report state transitions", func(st *testing.T) {
+		assert.NoError(st, client.TorrentStart(ctx, transmission.Filter{Ids: []string{"aaaa"}}))
+		event := nextEvent(st, events)
+		assert.Equal(st, transmission.EventStarted, event.Type)
+		assert.Equal(st, transmission.StatusStopped, event.Previous.Status)
+
+		s.UpdateTorrent("aaaa", func(t *transmission.Torrent) {
+			t.PercentDone = 1
+			t.Error = 3
+			t.ErrorString = "No data found!"
+		})
+		assert.Equal(st, transmission.EventCompleted, nextEvent(st, events).Type)
+		assert.Equal(st, transmission.EventErrorRaised, nextEvent(st, events).Type)
+
+		s.UpdateTorrent("aaaa", func(t *transmission.Torrent) { t.Error = 0 })
+		assert.Equal(st, transmission.EventErrorCleared, nextEvent(st, events).Type)
+
+		s.UpdateTorrent("bbbb", func(t *transmission.Torrent) { t.Status = transmission.StatusCheck })
+		assertNoEvent(st, events)
+		s.UpdateTorrent("bbbb", func(t *transmission.Torrent) { t.Status = transmission.StatusDownload })
+		assert.Equal(st, transmission.EventVerified, nextEvent(st, events).Type)
+
+		s.UpdateTorrent("bbbb", func(t *transmission.Torrent) { t.Labels = []string{"linux"} })
+		assert.Equal(st, transmission.EventLabelsChanged, nextEvent(st, events).Type)
+	})
+
+	t.Run("should report tracker errors once", func(st *testing.T) {
+		s.UpdateTorrent("bbbb", func(t *transmission.Torrent) {
+			t.TrackerStats = []transmission.TrackerStat{
+				{ID: 0, HasAnnounced: true, LastAnnounceSucceeded: true},
+				{ID: 1, HasAnnounced: true, LastAnnounceResult: "Connection refused"},
+			}
+		})
+
+		event := nextEvent(st, events)
+		assert.Equal(st, transmission.EventTrackerError, event.Type)
+		assert.Equal(st, "Connection refused", event.Tracker.LastAnnounceResult)
+		assertNoEvent(st, events)
+	})
+
+	t.Run("should report speed threshold crossings", func(st *testing.T) {
+		s.UpdateTorrent("bbbb", func(t *transmission.Torrent) { t.RateDownload = 5000 })
+		assert.Equal(st, transmission.EventDownloadAbove, nextEvent(st, events).Type)
+
+		s.UpdateTorrent("bbbb", func(t *transmission.Torrent) { t.RateDownload = 10 })
+		assert.Equal(st, transmission.EventDownloadBelow, nextEvent(st, events).Type)
+	})
+
+	t.Run("should survive daemon restarts", func(st *testing.T) {
+		s.AddTorrent(transmission.Torrent{Name: "fedora.iso", HashString: "cccc"})
+		assert.Equal(st, transmission.EventAdded, nextEvent(st, events).Type)
+
+		// the ids are swapped, but torrents are matched by hash
+		s.Restart("restarted", "cccc", "bbbb", "aaaa")
+		assertNoEvent(st, events)
+
+		assert.NoError(st, client.TorrentRemove(ctx, transmission.TorrentRemove{Ids: []string{"aaaa"}}))
+		event := nextEvent(st, events)
+		assert.Equal(st, transmission.EventRemoved, event.Type)
+		assert.Equal(st, "aaaa", event.Torrent.HashString)
+		assertNoEvent(st, events)
+	})
+
+	t.Run("should report poll errors and go on", func(st *testing.T) {
+		s.InjectFault(transmissiontest.Fault{Method: transmission.MethodTorrentGet, Result: "daemon busy", Times: 1})
+
+		event := nextEvent(st, events)
+		assert.Equal(st, transmission.EventWatchError, event.Type)
+		assert.Error(st, event.Err)
+
+		s.UpdateTorrent("cccc", func(t *transmission.Torrent) { t.Status = transmission.StatusSeed })
+		assert.Equal(st, transmission.EventStarted, nextEvent(st, events).Type)
+	})
+
+	t.Run("should close the channel with the context", func(st *testing.T) {
+		cancel()
+
+		for range events {
+		}
+	})
+}
+
+func TestClient_Watch_Restart(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+
+	s.AddTorrent(transmission.Torrent{Name: "debian.iso", HashString: "aaaa"})
+	s.AddTorrent(transmission.Torrent{Name: "ubuntu.iso", HashString: "bbbb"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := s.Client()
+	events := client.Watch(ctx, transmission.WatchOptions{Interval: time.Millisecond, FullSync: 1000})
+	assertNoEvent(t, events)
+
+	t.Run("should resync when the restarted daemon lists no active torrents", func(st *testing.T) {
+		s.SetIdle(true)
+		s.Restart("restarted", "bbbb", "aaaa")
+		assertNoEvent(st, events)
+
+		// bbbb now has the id aaaa had before the restart
+		assert.NoError(st, client.TorrentRemove(ctx, transmission.TorrentRemove{Ids: []string{"bbbb"}}))
+		event := nextEvent(st, events)
+		assert.Equal(st, transmission.EventRemoved, event.Type)
+		assert.Equal(st, "bbbb", event.Torrent.HashString)
+		assertNoEvent(st, events)
+	})
+}
+
+func TestClient_Watch_Initial(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+
+	s.AddTorrent(transmission.Torrent{Name: "debian.iso", HashString: "aaaa"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := s.Client().Watch(ctx, transmission.WatchOptions{Interval: time.Millisecond, Initial: true})
+
+	event := nextEvent(t, events)
+	assert.Equal(t, transmission.EventAdded, event.Type)
+	assert.Equal(t, "aaaa", event.Torrent.HashString)
+}
+
+func TestClient_WatchSession(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := s.Client()
+	events := client.WatchSession(ctx, transmission.WatchOptions{Interval: time.Millisecond})
+
+	assertNoEvent(t, events)
+
+	assert.NoError(t, client.SessionSet(ctx, transmission.SessionSet{AltSpeedEnabled: true, DownloadDir: "/media"}))
+
+	event := nextEvent(t, events)
+	assert.Equal(t, transmission.EventSessionChanged, event.Type)
+	assert.Equal(t, "download-dir", event.Change.Field)
+	assert.Equal(t, transmissiontest.DefaultDownloadDir, event.Change.Old)
+	assert.Equal(t, "/media", event.Change.New)
+
+	event = nextEvent(t, events)
+	assert.Equal(t, "alt-speed-enabled", event.Change.Field)
+	assert.Equal(t, false, event.Change.Old)
+	assert.Equal(t, true, event.Change.New)
+	assert.True(t, event.Change.Session.AltSpeedEnabled)
+
+	// torrents are not watched
+	s.AddTorrent(transmission.Torrent{Name: "debian.iso", HashString: "aaaa"})
+	assertNoEvent(t, events)
+}
+
+func TestClient_Watch_Session(t *testing.T) {
+	s := transmissiontest.NewServer()
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := s.Client()
+	events := client.Watch(ctx, transmission.WatchOptions{Interval: time.Millisecond, Session: true})
+	assertNoEvent(t, events)
+
+	s.AddTorrent(transmission.Torrent{Name: "debian.iso", HashString: "aaaa"})
+	assert.Equal(t, transmission.EventAdded, nextEvent(t, events).Type)
+
+	assert.NoError(t, client.SessionSet(ctx, transmission.SessionSet{PeerPort: 51414}))
+	event := nextEvent(t, events)
+	assert.Equal(t, transmission.EventSessionChanged, event.Type)
+	assert.Equal(t, "peer-port", event.Change.Field)
+	assert.Equal(t, int64(51414), event.Change.New)
+}